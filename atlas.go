@@ -0,0 +1,168 @@
+package tilepix
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/gopxl/pixel"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+    _   _    _
+   /_\ | |_ | | __ _  ___
+  / _ \|  _|| |/ _` |(_-<
+ /_/ \_\\__||_|\__,_|/__/
+*/
+
+// PackTilesets packs the pictures of all tilesets of the map into a single atlas picture, leaving padding pixels clear
+// around each.  Tile layers are then drawn with one batch however many tilesets they use, and TileLayer.Batch may be
+// used for any layer.  This is optional, and should be called after the map is read and before it is first drawn;
+// all tileset pictures are loaded, and the sprites of tiles and tile objects are recalculated when next drawn.  The
+// pictures of the tilesets themselves are packed, so calling this again repacks them.
+func (m *Map) PackTilesets(padding int) error {
+	images := make([]image.Image, len(m.Tilesets))
+	for i, ts := range m.Tilesets {
+		ts.setSprite()
+		pic, ok := ts.ownPicture.(*pixel.PictureData)
+		if !ok {
+			log.WithError(ErrInvalidPicture).WithField("Tileset", ts).Error("Map.PackTilesets: could not load tileset picture")
+			return ErrInvalidPicture
+		}
+		images[i] = pic.Image()
+	}
+
+	packed, rects := packImages(images, padding)
+	atlas := pixel.PictureDataFromImage(packed)
+	log.WithFields(log.Fields{"Tileset count": len(m.Tilesets), "Bounds": atlas.Bounds()}).Debug("Map.PackTilesets: packed tilesets")
+
+	for i, ts := range m.Tilesets {
+		ts.picture = atlas
+		ts.sprite = pixel.NewSprite(atlas, atlas.Bounds())
+		ts.atlasOrigin = pictureRect(rects[i], packed.Bounds().Dy()).Min
+	}
+
+	m.resetSprites()
+
+	return nil
+}
+
+// WithTileExtrusion returns an Option which rebuilds the picture of each tileset of the map, as it is read, with the
+// edge pixels of every tile extruded by the number of pixels provided.  Tiles are still drawn at their normal size, but
+// when the map is scaled the pixels sampled at the edges of tiles are those of the tile itself rather than its
+// neighbours, which removes the seams between tiles.  Extruded tilesets may still be packed with Map.PackTilesets.
+func WithTileExtrusion(extrusion int) Option {
+	return func(o *readOptions) {
+		o.extrusion = extrusion
+	}
+}
+
+// extrudeTiles rebuilds the picture of each tileset of the map with the edges of every tile extruded, see
+// WithTileExtrusion.
+func (m *Map) extrudeTiles(extrusion int) error {
+	for _, ts := range m.Tilesets {
+		pic, ok := ts.setSprite().(*pixel.PictureData)
+		if !ok {
+			log.WithError(ErrInvalidPicture).WithField("Tileset", ts).Error("Map.extrudeTiles: could not load tileset picture")
+			return ErrInvalidPicture
+		}
+		ts.extrude(pic, extrusion)
+	}
+
+	m.resetSprites()
+
+	return nil
+}
+
+// extrudeEdges fills the extrusion pixels around the area of the image provided with the colours of the nearest pixel
+// within the area.
+func extrudeEdges(img *image.NRGBA, r image.Rectangle, extrusion int) {
+	if r.Empty() {
+		return
+	}
+
+	outer := r.Inset(-extrusion).Intersect(img.Bounds())
+	for y := outer.Min.Y; y < outer.Max.Y; y++ {
+		for x := outer.Min.X; x < outer.Max.X; x++ {
+			if image.Pt(x, y).In(r) {
+				continue
+			}
+
+			nearest := image.Pt(min(max(x, r.Min.X), r.Max.X-1), min(max(y, r.Min.Y), r.Max.Y-1))
+			img.SetNRGBA(x, y, img.NRGBAAt(nearest.X, nearest.Y))
+		}
+	}
+}
+
+// imageRect converts an area of a picture into the area of the image the picture was created from; the inverse of
+// pictureRect.
+func imageRect(r pixel.Rect, imageHeight int) image.Rectangle {
+	return image.Rect(int(r.Min.X), imageHeight-int(r.Max.Y), int(r.Max.X), imageHeight-int(r.Min.Y))
+}
+
+// loadImageFile loads and decodes the image at the path provided.
+func loadImageFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("loadImageFile: could not open file")
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("loadImageFile: could not decode image")
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// packImages arranges the images into rows of a single image, tallest first, and returns the packed image along with
+// the area each image occupies within it.  The padding is left clear around each image.
+func packImages(images []image.Image, padding int) (*image.NRGBA, []image.Rectangle) {
+	order := make([]int, len(images))
+	area, maxWidth := 0, 0
+	for i, img := range images {
+		order[i] = i
+		size := img.Bounds().Size().Add(image.Pt(2*padding, 2*padding))
+		area += size.X * size.Y
+		maxWidth = max(maxWidth, size.X)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return images[order[a]].Bounds().Dy() > images[order[b]].Bounds().Dy()
+	})
+
+	// Aim for a roughly square image, which must be at least as wide as the widest image.
+	width := max(maxWidth, int(math.Ceil(math.Sqrt(float64(area)))))
+
+	rects := make([]image.Rectangle, len(images))
+	x, y, rowHeight := 0, 0, 0
+	for _, i := range order {
+		size := images[i].Bounds().Size().Add(image.Pt(2*padding, 2*padding))
+		if x+size.X > width {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+
+		min := image.Pt(x+padding, y+padding)
+		rects[i] = image.Rectangle{Min: min, Max: min.Add(images[i].Bounds().Size())}
+		x += size.X
+		rowHeight = max(rowHeight, size.Y)
+	}
+
+	packed := image.NewNRGBA(image.Rect(0, 0, width, y+rowHeight))
+	for i, img := range images {
+		draw.Draw(packed, rects[i], img, img.Bounds().Min, draw.Src)
+	}
+
+	return packed, rects
+}
+
+// pictureRect converts an area of an image into the area of the picture created from that image.  Pictures have their
+// origin at the bottom-left, where images have it at the top-left.
+func pictureRect(r image.Rectangle, imageHeight int) pixel.Rect {
+	return pixel.R(float64(r.Min.X), float64(imageHeight-r.Max.Y), float64(r.Max.X), float64(imageHeight-r.Min.Y))
+}
@@ -0,0 +1,119 @@
+package tilepix
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gopxl/pixel"
+)
+
+func TestPackImages(t *testing.T) {
+	sizes := []image.Point{{16, 16}, {32, 48}, {8, 8}, {16, 32}, {24, 8}}
+	var images []image.Image
+	for i, size := range sizes {
+		img := image.NewNRGBA(image.Rectangle{Max: size})
+		c := color.NRGBA{R: uint8(i * 50), A: 0xff}
+		for x := 0; x < size.X; x++ {
+			for y := 0; y < size.Y; y++ {
+				img.Set(x, y, c)
+			}
+		}
+		images = append(images, img)
+	}
+
+	packed, rects := packImages(images, 1)
+
+	for i, r := range rects {
+		if r.Size() != sizes[i] {
+			t.Errorf("Image %d packed with size %v, want %v", i, r.Size(), sizes[i])
+		}
+		if !r.Inset(-1).In(packed.Bounds()) {
+			t.Errorf("Image %d packed at %v, outside of %v with padding", i, r, packed.Bounds())
+		}
+		for j, other := range rects[:i] {
+			if r.Inset(-1).Overlaps(other) {
+				t.Errorf("Image %d at %v overlaps image %d at %v", i, r, j, other)
+			}
+		}
+		if got, want := packed.NRGBAAt(r.Min.X, r.Min.Y), (color.NRGBA{R: uint8(i * 50), A: 0xff}); got != want {
+			t.Errorf("Image %d colour = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPictureRect(t *testing.T) {
+	got := pictureRect(image.Rect(2, 4, 10, 12), 20)
+	if want := pixel.R(2, 8, 10, 16); got != want {
+		t.Errorf("pictureRect() = %v, want %v", got, want)
+	}
+}
+
+func TestMap_PackTilesets(t *testing.T) {
+	m, err := ReadFile("testdata/multi_tileset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sample the centre of some tiles before packing, the same colours must be found in the atlas.
+	type sample struct {
+		ts  *Tileset
+		id  ID
+		col pixel.RGBA
+	}
+	var samples []sample
+	for _, ts := range m.Tilesets {
+		for _, id := range []ID{0, 1, ID(ts.Tilecount - 1)} {
+			pic := ts.setSprite().(*pixel.PictureData)
+			samples = append(samples, sample{ts: ts, id: id, col: pic.Color(ts.tileFrame(id).Center())})
+		}
+	}
+
+	// Packing again repacks the tilesets' own pictures, rather than the atlas.
+	for _, padding := range []int{1, 2} {
+		if err := m.PackTilesets(padding); err != nil {
+			t.Fatal(err)
+		}
+
+		atlas := m.Tilesets[0].setSprite()
+		if m.Tilesets[1].setSprite() != atlas {
+			t.Fatal("Expected tilesets to share a picture")
+		}
+		for _, s := range samples {
+			if got := atlas.(*pixel.PictureData).Color(s.ts.tileFrame(s.id).Center()); got != s.col {
+				t.Errorf("Padding %d tileset %s tile %d colour = %v, want %v", padding, s.ts.Name, s.id, got, s.col)
+			}
+		}
+	}
+
+	l := m.GetTileLayerByName("mixed")
+	if len(l.pictures()) != 1 {
+		t.Errorf("Expected layer to use a single picture, got %d", len(l.pictures()))
+	}
+	if _, err := l.Batch(); err != nil {
+		t.Errorf("Expected single batch for packed layer: %v", err)
+	}
+}
+
+func TestWithTileExtrusion(t *testing.T) {
+	m, err := ReadFile("testdata/spacing_unsized.tmx", WithTileExtrusion(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := m.Tilesets[0]
+	pic := ts.setSprite().(*pixel.PictureData)
+	for id, want := range []pixel.RGBA{pixel.RGB(1, 0, 0), pixel.RGB(0, 1, 0), pixel.RGB(0, 0, 1), pixel.RGB(1, 1, 0)} {
+		frame := ts.tileFrame(ID(id))
+		if frame.Size() != pixel.V(4, 4) {
+			t.Errorf("Tile %d frame %v, want size 4x4", id, frame)
+		}
+		// The pixels just outside of the frame are extruded from the edges of the tile.
+		for _, v := range frame.Vertices() {
+			at := v.Add(v.Sub(frame.Center()).Unit().Scaled(1.5))
+			if got := pic.Color(at); got != want {
+				t.Errorf("Tile %d colour at %v = %v, want %v", id, at, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package tilepix
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+   ___                                      _
+  / __| ___  _ __   _ __  _ _  ___  ___ ___(_) ___  _ _
+ | (__ / _ \| '  \ | '_ \| '_|/ -_)(_-<(_-<| |/ _ \| ' \
+  \___|\___/|_|_|_|| .__/|_|  \___|/__//__/|_|\___/|_||_|
+                   |_|
+*/
+
+// Decompressor creates a reader which will decompress the data read from r.  If the reader returned also implements
+// io.Closer, it will be closed once all data has been read.
+type Decompressor func(r io.Reader) (io.Reader, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		"":     noDecompressor,
+		"gzip": gzipDecompressor,
+		"zlib": zlibDecompressor,
+		"zstd": zstdDecompressor,
+	}
+)
+
+// RegisterCompression registers a Decompressor for the compression name used in the `compression` attribute of layer
+// data.  Registering a name which is already registered, including the built in "gzip", "zlib" and "zstd", will
+// replace the existing Decompressor.
+func RegisterCompression(name string, d Decompressor) {
+	log.WithField("Compression", name).Debug("RegisterCompression: registering decompressor")
+
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	decompressors[name] = d
+}
+
+func getDecompressor(name string) (Decompressor, bool) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+
+	d, ok := decompressors[name]
+	return d, ok
+}
+
+func gzipDecompressor(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func noDecompressor(r io.Reader) (io.Reader, error) {
+	return r, nil
+}
+
+func zlibDecompressor(r io.Reader) (io.Reader, error) {
+	return zlib.NewReader(r)
+}
+
+func zstdDecompressor(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.IOReadCloser(), nil
+}
@@ -2,8 +2,6 @@ package tilepix
 
 import (
 	"bytes"
-	"compress/gzip"
-	"compress/zlib"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -29,45 +27,72 @@ type Data struct {
 	RawData     []byte `xml:",innerxml"`
 	// DataTiles is only used when layer encoding is XML.
 	DataTiles []*DataTile `xml:"tile"`
+	// Chunks is only used by infinite maps; the data is split into chunks rather than held directly.
+	Chunks []*Chunk `xml:"chunk"`
 }
 
 func (d *Data) String() string {
 	return fmt.Sprintf("Data{Compression: %s, DataTiles count: %d}", d.Compression, len(d.DataTiles))
 }
 
+// decode will decode the data into a slice of GIDs, checking that there are exactly width*height entries.
+func (d *Data) decode(width, height int) ([]GID, error) {
+	log.WithField("Encoding", d.Encoding).Debug("Data.decode: determining encoding")
+
+	switch d.Encoding {
+	case "csv":
+		return d.decodeLayerCSV(width, height)
+	case "base64":
+		return d.decodeLayerBase64(width, height)
+	case "":
+		// XML "encoding"
+		return d.decodeLayerXML(width, height)
+	}
+
+	log.WithError(ErrUnknownEncoding).Error("Data.decode: unrecognised encoding")
+	return nil, ErrUnknownEncoding
+}
+
 func (d *Data) decodeBase64() (data []byte, err error) {
 	rawData := bytes.TrimSpace(d.RawData)
 	r := bytes.NewReader(rawData)
 
 	encr := base64.NewDecoder(base64.StdEncoding, r)
 
-	var comr io.Reader
-	switch d.Compression {
-	case "gzip":
-		log.Debug("decodeBase64: compression is gzip")
-
-		comr, err = gzip.NewReader(encr)
-		if err != nil {
-			return
-		}
-	case "zlib":
-		log.Debug("decodeBase64: compression is zlib")
-
-		comr, err = zlib.NewReader(encr)
-		if err != nil {
-			return
-		}
-	case "":
-		log.Debug("decodeBase64: no compression")
-
-		comr = encr
-	default:
+	decompress, ok := getDecompressor(d.Compression)
+	if !ok {
 		err = ErrUnknownCompression
 		log.WithError(ErrUnknownCompression).WithField("Compression", d.Compression).Error("decodeBase64: unable to handle this compression type")
 		return
 	}
 
-	return ioutil.ReadAll(comr)
+	log.WithField("Compression", d.Compression).Debug("decodeBase64: decompressing")
+
+	comr, err := decompress(encr)
+	if err != nil {
+		log.WithError(err).WithField("Compression", d.Compression).Error("decodeBase64: could not create decompressor")
+		return
+	}
+	c, isCloser := comr.(io.Closer)
+	if isCloser {
+		// Defer close, in case reading fails
+		defer c.Close()
+	}
+
+	data, err = ioutil.ReadAll(comr)
+	if err != nil {
+		log.WithError(err).WithField("Compression", d.Compression).Error("decodeBase64: could not decompress data")
+		return nil, err
+	}
+
+	if isCloser {
+		if err := c.Close(); err != nil {
+			log.WithError(err).WithField("Compression", d.Compression).Error("decodeBase64: could not close decompressor")
+			return nil, err
+		}
+	}
+
+	return data, nil
 }
 
 func (d *Data) decodeCSV() ([]GID, error) {
@@ -93,3 +118,90 @@ func (d *Data) decodeCSV() ([]GID, error) {
 	}
 	return gids, nil
 }
+
+func (d *Data) decodeLayerXML(width, height int) ([]GID, error) {
+	if len(d.DataTiles) != width*height {
+		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length datatiles": len(d.DataTiles), "W*H": width * height}).Error("Data.decodeLayerXML: data length mismatch")
+		return nil, ErrInvalidDecodedDataLen
+	}
+
+	gids := make([]GID, len(d.DataTiles))
+	for i := 0; i < len(gids); i++ {
+		gids[i] = d.DataTiles[i].GID
+	}
+
+	return gids, nil
+}
+
+func (d *Data) decodeLayerCSV(width, height int) ([]GID, error) {
+	gids, err := d.decodeCSV()
+	if err != nil {
+		log.WithError(err).Error("Data.decodeLayerCSV: could not decode CSV")
+		return nil, err
+	}
+
+	if len(gids) != width*height {
+		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length GIDSs": len(gids), "W*H": width * height}).Error("Data.decodeLayerCSV: data length mismatch")
+		return nil, ErrInvalidDecodedDataLen
+	}
+
+	return gids, nil
+}
+
+func (d *Data) decodeLayerBase64(width, height int) ([]GID, error) {
+	dataBytes, err := d.decodeBase64()
+	if err != nil {
+		log.WithError(err).Error("Data.decodeLayerBase64: could not decode base64")
+		return nil, err
+	}
+
+	if len(dataBytes) != width*height*4 {
+		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length databytes": len(dataBytes), "W*H": width * height}).Error("Data.decodeLayerBase64: data length mismatch")
+		return nil, ErrInvalidDecodedDataLen
+	}
+
+	gids := make([]GID, width*height)
+
+	j := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			gid := GID(dataBytes[j]) +
+				GID(dataBytes[j+1])<<8 +
+				GID(dataBytes[j+2])<<16 +
+				GID(dataBytes[j+3])<<24
+			j += 4
+
+			gids[y*width+x] = gid
+		}
+	}
+
+	return gids, nil
+}
+
+// Chunk is a TMX file structure holding a rectangular section of layer data.  Chunks are only used by infinite maps.
+type Chunk struct {
+	// X and Y are the tile co-ordinates of the top-left of the chunk; these can be negative.
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+	// Width and Height are the dimensions of the chunk in tiles.
+	Width     int         `xml:"width,attr"`
+	Height    int         `xml:"height,attr"`
+	RawData   []byte      `xml:",innerxml"`
+	DataTiles []*DataTile `xml:"tile"`
+}
+
+func (c *Chunk) String() string {
+	return fmt.Sprintf("Chunk{Position: %d,%d, Size: %dx%d}", c.X, c.Y, c.Width, c.Height)
+}
+
+// decode will decode the chunk into a slice of GIDs, using the encoding and compression of the parent data.
+func (c *Chunk) decode(parent *Data) ([]GID, error) {
+	d := &Data{
+		Encoding:    parent.Encoding,
+		Compression: parent.Compression,
+		RawData:     c.RawData,
+		DataTiles:   c.DataTiles,
+	}
+
+	return d.decode(c.Width, c.Height)
+}
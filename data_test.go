@@ -1,6 +1,11 @@
 package tilepix
 
-import "testing"
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"testing"
+)
 
 func TestData_String(t *testing.T) {
 	type fields struct {
@@ -30,3 +35,57 @@ func TestData_String(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterCompression(t *testing.T) {
+	// Restore the registry, so the test compression is not registered for other tests.
+	decompressorsMu.RLock()
+	registered := make(map[string]Decompressor, len(decompressors))
+	for name, d := range decompressors {
+		registered[name] = d
+	}
+	decompressorsMu.RUnlock()
+	t.Cleanup(func() {
+		decompressorsMu.Lock()
+		decompressors = registered
+		decompressorsMu.Unlock()
+	})
+
+	RegisterCompression("test-identity", func(r io.Reader) (io.Reader, error) {
+		return r, nil
+	})
+
+	tests := []struct {
+		name        string
+		compression string
+		want        []byte
+		wantErr     error
+	}{
+		{
+			name:        "registered compression",
+			compression: "test-identity",
+			want:        []byte("tilepix"),
+		},
+		{
+			name:        "unknown compression",
+			compression: "test-unknown",
+			wantErr:     ErrUnknownCompression,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Data{
+				Encoding:    "base64",
+				Compression: tt.compression,
+				RawData:     []byte(base64.StdEncoding.EncodeToString([]byte("tilepix"))),
+			}
+
+			got, err := d.decodeBase64()
+			if err != tt.wantErr {
+				t.Fatalf("decodeBase64() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decodeBase64() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 module github.com/bcvery1/tilepix
 
-go 1.21
+go 1.22
 
 require (
 	github.com/gopxl/pixel v1.0.0
+	github.com/klauspost/compress v1.18.0
 	github.com/sirupsen/logrus v1.4.1
 )
 
@@ -0,0 +1,249 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/gopxl/pixel"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+   ___                        _
+  / __| _ _  ___  _  _  _ __ | |    __ _  _  _  ___  _ _
+ | (_ || '_|/ _ \| || || '_ \| |__ / _` || || |/ -_)| '_|
+  \___||_|  \___/ \_,_|| .__/|____|\__,_| \_, |\___||_|
+                       |_|                |__/
+*/
+
+// GroupLayer is a TMX file structure which holds a Tiled group; a group can contain layers of any type, including
+// further groups.  The offset, opacity, visibility and tint of a group apply to all layers within it.
+type GroupLayer struct {
+	Name       string     `xml:"name,attr"`
+	OffSetX    float64    `xml:"offsetx,attr"`
+	OffSetY    float64    `xml:"offsety,attr"`
+	Opacity    float64    `xml:"opacity,attr"`
+	Visible    bool       `xml:"visible,attr"`
+	TintColor  string     `xml:"tintcolor,attr"`
+	Properties Properties `xml:"properties>property"`
+	// ParallaxX and ParallaxY are the factors the group scrolls by relative to the camera, see Map.DrawAllParallax.
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	// TileLayers, ObjectGroups, ImageLayers and Groups are the layers directly within this group.
+	TileLayers   []*TileLayer   `xml:"layer"`
+	ObjectGroups []*ObjectGroup `xml:"objectgroup"`
+	ImageLayers  []*ImageLayer  `xml:"imagelayer"`
+	Groups       []*GroupLayer  `xml:"group"`
+	// Layers holds the layers directly within this group in the order they appear in Tiled, bottom-most first.
+	Layers []Layer `xml:"-"`
+
+	// tint is the colour mask parsed from TintColor, it is nil when the group is not tinted.  It is set when the group is
+	// read and by SetTintColor, so that the tint is only parsed once.
+	tint *pixel.RGBA
+
+	// parentGroup is the group which contains this group, it is nil for top-level groups.
+	parentGroup *GroupLayer
+	// parentMap is the map which contains this object
+	parentMap *Map
+}
+
+// EffectiveOffset returns the offset of the group, including the offsets of all groups containing it.  The offset is
+// in game co-ordinates, so the Y component is inverted from that set in Tiled.
+func (g *GroupLayer) EffectiveOffset() pixel.Vec {
+	return pixel.V(g.OffSetX, -g.OffSetY).Add(g.parentGroup.effectiveOffset())
+}
+
+// EffectiveOpacity returns the opacity of the group, multiplied by the opacity of all groups containing it.
+func (g *GroupLayer) EffectiveOpacity() float64 {
+	return g.Opacity * g.parentGroup.effectiveOpacity()
+}
+
+// EffectiveParallax returns the parallax factors of the group, multiplied by those of all groups containing it.
+func (g *GroupLayer) EffectiveParallax() pixel.Vec {
+	return pixel.V(g.ParallaxX, g.ParallaxY).ScaledXY(g.parentGroup.effectiveParallax())
+}
+
+// EffectiveTint returns the tint colour of the group, multiplied by the tint of all groups containing it.
+func (g *GroupLayer) EffectiveTint() pixel.RGBA {
+	return tintMask(g.tint).Mul(g.parentGroup.effectiveTint())
+}
+
+// EffectiveVisible returns whether the group is visible; a group is hidden if any group containing it is hidden.
+func (g *GroupLayer) EffectiveVisible() bool {
+	return g.Visible && g.parentGroup.effectiveVisible()
+}
+
+// Props returns the custom properties of the group.
+func (g *GroupLayer) Props() Properties {
+	return g.Properties
+}
+
+// SetOpacity sets the opacity of the group, from 0 for transparent to 1 for opaque.  The opacity of the group is
+// multiplied with that of every layer within it.
+func (g *GroupLayer) SetOpacity(opacity float64) {
+	g.Opacity = opacity
+}
+
+// SetTintColor sets the tint colour of the group, which is multiplied with the colour of every layer within it.  A
+// nil colour removes the tint.
+func (g *GroupLayer) SetTintColor(c color.Color) {
+	g.TintColor = formatColor(c)
+	g.tint = parseTint(g.TintColor)
+}
+
+// SetVisible sets whether the group is drawn.  Layers within a hidden group are not drawn.
+func (g *GroupLayer) SetVisible(visible bool) {
+	g.Visible = visible
+}
+
+func (g *GroupLayer) String() string {
+	return fmt.Sprintf(
+		"GroupLayer{Name: '%s', TileLayers: %v, Object layers: %v, Image layers: %v, Groups: %v}",
+		g.Name,
+		g.TileLayers,
+		g.ObjectGroups,
+		g.ImageLayers,
+		g.Groups,
+	)
+}
+
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the group.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (g *GroupLayer) UnmarshalProperties(v interface{}) error {
+	return g.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, defaulting any attributes Tiled omits and recording the order of Layers.
+func (g *GroupLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type groupLayer GroupLayer
+	var gl groupLayer
+	setLayerDefaults(&gl.Opacity, &gl.Visible, &gl.ParallaxX, &gl.ParallaxY)
+	if err := decodeAttrs(start, &gl); err != nil {
+		log.WithError(err).Error("GroupLayer.UnmarshalXML: could not decode attributes")
+		return err
+	}
+
+	var ll layerList
+	err := decodeChildren(d, func(child xml.StartElement) error {
+		if ok, err := ll.decode(d, child); ok {
+			return err
+		}
+
+		if child.Name.Local == "properties" {
+			ps, err := decodeProperties(d, child)
+			gl.Properties = append(gl.Properties, ps...)
+			return err
+		}
+		return d.Skip()
+	})
+	if err != nil {
+		log.WithError(err).Error("GroupLayer.UnmarshalXML: could not decode group")
+		return err
+	}
+
+	*g = GroupLayer(gl)
+	g.tint = parseTint(g.TintColor)
+	g.TileLayers, g.ObjectGroups, g.ImageLayers, g.Groups = ll.tileLayers, ll.objectGroups, ll.imageLayers, ll.groups
+	g.Layers = ll.layers()
+	return nil
+}
+
+// allGroups returns this group and every group nested within it, parents before their children.
+func (g *GroupLayer) allGroups() []*GroupLayer {
+	groups := []*GroupLayer{g}
+	for _, child := range g.Groups {
+		groups = append(groups, child.allGroups()...)
+	}
+
+	return groups
+}
+
+// The effective methods below are used by child layers, and handle a nil group; meaning the layer is not within a
+// group.
+
+func (g *GroupLayer) effectiveOffset() pixel.Vec {
+	if g == nil {
+		return pixel.ZV
+	}
+	return g.EffectiveOffset()
+}
+
+func (g *GroupLayer) effectiveOpacity() float64 {
+	if g == nil {
+		return 1
+	}
+	return g.EffectiveOpacity()
+}
+
+func (g *GroupLayer) effectiveParallax() pixel.Vec {
+	if g == nil {
+		return pixel.V(1, 1)
+	}
+	return g.EffectiveParallax()
+}
+
+func (g *GroupLayer) effectiveTint() pixel.RGBA {
+	if g == nil {
+		return pixel.Alpha(1)
+	}
+	return g.EffectiveTint()
+}
+
+func (g *GroupLayer) effectiveVisible() bool {
+	if g == nil {
+		return true
+	}
+	return g.EffectiveVisible()
+}
+
+// findGroup returns the group at the path provided, relative to this group.  Path elements are group names separated by
+// a forward slash.
+func (g *GroupLayer) findGroup(path []string) *GroupLayer {
+	if len(path) == 0 {
+		return g
+	}
+
+	for _, child := range g.Groups {
+		if child.Name == path[0] {
+			if found := child.findGroup(path[1:]); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkChildren sets this group as the parent group of all layers directly within it, and recursively does the same for
+// all groups within it.
+func (g *GroupLayer) linkChildren() {
+	for _, l := range g.TileLayers {
+		l.parentGroup = g
+	}
+	for _, og := range g.ObjectGroups {
+		og.parentGroup = g
+	}
+	for _, il := range g.ImageLayers {
+		il.parentGroup = g
+	}
+	for _, child := range g.Groups {
+		child.parentGroup = g
+		child.linkChildren()
+	}
+}
+
+func (g *GroupLayer) setParent(m *Map) {
+	g.parentMap = m
+
+	for _, p := range g.Properties {
+		p.setParent(m)
+	}
+}
+
+// splitLayerPath splits a layer path, such as "world/ground", into the path of its groups and the layers' name.
+func splitLayerPath(path string) (groups []string, name string) {
+	parts := strings.Split(path, "/")
+	return parts[:len(parts)-1], parts[len(parts)-1]
+}
@@ -0,0 +1,70 @@
+package tilepix_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+	"github.com/gopxl/pixel"
+)
+
+func TestGroupLayer(t *testing.T) {
+	for _, path := range []string{"testdata/groups.tmx", "testdata/groups.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ground := m.GetTileLayerByName("ground")
+			if ground == nil || ground.DecodedTiles[0].ID != 11 {
+				t.Fatalf("Expected the first layer named ground, got %v", ground)
+			}
+			if got, want := ground.EffectiveOffset(), pixel.V(11, -22); got != want {
+				t.Errorf("TileLayer.EffectiveOffset() = %v, want %v", got, want)
+			}
+			if got := ground.EffectiveOpacity(); got != 0.25 {
+				t.Errorf("TileLayer.EffectiveOpacity() = %v, want 0.25", got)
+			}
+			if got, want := ground.EffectiveTint(), pixel.RGB(1, 0, 0); got != want {
+				t.Errorf("TileLayer.EffectiveTint() = %v, want %v", got, want)
+			}
+			if !ground.EffectiveVisible() {
+				t.Error("Expected ground layer to be visible")
+			}
+
+			innerGround := m.GetTileLayerByName("world/inner/ground")
+			if innerGround == nil || innerGround == ground || innerGround.DecodedTiles[0].ID != 0 {
+				t.Fatalf("Expected the layer at world/inner/ground, got %v", innerGround)
+			}
+			if innerGround.EffectiveVisible() {
+				t.Error("Expected layer within hidden group to be hidden")
+			}
+			if tint := innerGround.EffectiveTint(); math.Abs(tint.R-128.0/255) > 1e-9 || tint.G != 0 {
+				t.Errorf("TileLayer.EffectiveTint() = %v, want inherited tint", tint)
+			}
+
+			if l := m.GetTileLayerByName("world/missing"); l != nil {
+				t.Errorf("Expected nil for missing layer path, got %v", l)
+			}
+			if g := m.GetGroupLayerByName("world/inner"); g == nil || g.Name != "inner" {
+				t.Errorf("Expected inner group, got %v", g)
+			}
+			if il := m.GetImageLayerByName("background"); il == nil || il.EffectiveVisible() {
+				t.Errorf("Expected hidden background image layer, got %v", il)
+			}
+
+			objs := m.GetObjectByName("trigger")
+			if len(objs) != 1 {
+				t.Fatalf("Expected 1 object named trigger, got %d", len(objs))
+			}
+			rect, err := objs[0].GetRect()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := pixel.R(10, 124, 26, 140); rect != want {
+				t.Errorf("Object.GetRect() = %v, want %v", rect, want)
+			}
+		})
+	}
+}
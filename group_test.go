@@ -0,0 +1,81 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"image/color"
+	"testing"
+
+	"github.com/gopxl/pixel"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestGroupLayer_String(t *testing.T) {
+	type fields struct {
+		Name string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   string
+	}{
+		{
+			name:   "Basic string",
+			fields: fields{Name: "name gl"},
+			want:   "GroupLayer{Name: 'name gl', TileLayers: [], Object layers: [], Image layers: [], Groups: []}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GroupLayer{
+				Name: tt.fields.Name,
+			}
+			if got := g.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupLayer_tint(t *testing.T) {
+	hook := test.NewLocal(log.StandardLogger())
+	t.Cleanup(func() { log.StandardLogger().ReplaceHooks(make(log.LevelHooks)) })
+
+	var g GroupLayer
+	if err := xml.Unmarshal([]byte(`<group tintcolor="#zz"><layer tintcolor="#ff0000"/></group>`), &g); err != nil {
+		t.Fatal(err)
+	}
+	l := g.TileLayers[0]
+	l.parentGroup = &g
+
+	// The invalid tint is reported once, when it is read, rather than each time the layer is drawn.
+	warnings := func() int {
+		n := 0
+		for _, e := range hook.AllEntries() {
+			if e.Level == log.WarnLevel {
+				n++
+			}
+		}
+		return n
+	}
+	if got := warnings(); got != 1 {
+		t.Fatalf("Got %d warnings when reading the group, want 1", got)
+	}
+	for i := 0; i < 3; i++ {
+		if got, want := l.EffectiveTint(), pixel.RGB(1, 0, 0); got != want {
+			t.Errorf("EffectiveTint() = %v, want %v", got, want)
+		}
+	}
+	if got := warnings(); got != 1 {
+		t.Errorf("Got %d warnings after drawing, want 1", got)
+	}
+
+	g.SetTintColor(color.NRGBA{G: 0xff, A: 0xff})
+	if got, want := l.EffectiveTint(), pixel.RGB(0, 0, 0); got != want {
+		t.Errorf("EffectiveTint() = %v, want %v after SetTintColor", got, want)
+	}
+	g.SetTintColor(nil)
+	if got, want := l.EffectiveTint(), pixel.RGB(1, 0, 0); got != want {
+		t.Errorf("EffectiveTint() = %v, want %v after removing the tint", got, want)
+	}
+}
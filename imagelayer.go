@@ -1,7 +1,9 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
+	"image/color"
 
 	"github.com/gopxl/pixel"
 	log "github.com/sirupsen/logrus"
@@ -17,36 +19,109 @@ import (
 
 // ImageLayer is a TMX file structure which references an image layer, with associated properties.
 type ImageLayer struct {
-	Locked  bool    `xml:"locked,attr"`
-	Name    string  `xml:"name,attr"`
-	OffSetX float64 `xml:"offsetx,attr"`
-	OffSetY float64 `xml:"offsety,attr"`
-	Opacity float64 `xml:"opacity,attr"`
-	Image   *Image  `xml:"image"`
+	Locked    bool    `xml:"locked,attr"`
+	Name      string  `xml:"name,attr"`
+	OffSetX   float64 `xml:"offsetx,attr"`
+	OffSetY   float64 `xml:"offsety,attr"`
+	Opacity   float64 `xml:"opacity,attr"`
+	Visible   bool    `xml:"visible,attr"`
+	TintColor string  `xml:"tintcolor,attr"`
+	Image     *Image  `xml:"image"`
+	// ParallaxX and ParallaxY are the factors the layer scrolls by relative to the camera, see Map.DrawAllParallax.
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
 
+	// tint is the colour mask parsed from TintColor, it is nil when the layer is not tinted.  It is set when the layer is
+	// read and by SetTintColor, so that the tint is only parsed once.
+	tint *pixel.RGBA
+
+	// parentGroup is the group which contains this layer, it is nil for top-level layers.
+	parentGroup *GroupLayer
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
-// Draw will draw the image layer to the target provided, shifted with the provided matrix.
+// Draw will draw the image layer to the target provided, shifted with the provided matrix.  The image is drawn with the
+// effective opacity and tint of the layer, and hidden layers are not drawn.
 func (im *ImageLayer) Draw(target pixel.Target, mat pixel.Matrix) error {
+	if !im.EffectiveVisible() {
+		return nil
+	}
+
 	if err := im.Image.initSprite(); err != nil {
 		log.WithError(err).Error("ImageLayer.Draw: could not initialise image sprite")
 		return err
 	}
 
 	// Shift image right-down by half its' dimensions.
-	// Shift image by layer offset.
-	mat = mat.Moved(pixel.V(float64(im.Image.Width/2), float64(im.Image.Height/-2))).Moved(pixel.V(im.OffSetX, -im.OffSetY))
+	// Shift image by layer offset, including the offsets of any groups containing the layer.
+	mat = mat.Moved(pixel.V(float64(im.Image.Width/2), float64(im.Image.Height/-2))).Moved(im.EffectiveOffset())
 
-	im.Image.sprite.Draw(target, mat)
+	im.Image.sprite.DrawColorMask(target, mat, colorMask(im))
 	return nil
 }
 
+// EffectiveOffset returns the offset of the image layer, including the offsets of all groups containing it.  The offset
+// is in game co-ordinates, so the Y component is inverted from that set in Tiled.
+func (im *ImageLayer) EffectiveOffset() pixel.Vec {
+	return pixel.V(im.OffSetX, -im.OffSetY).Add(im.parentGroup.effectiveOffset())
+}
+
+// EffectiveOpacity returns the opacity of the image layer, multiplied by the opacity of all groups containing it.
+func (im *ImageLayer) EffectiveOpacity() float64 {
+	return im.Opacity * im.parentGroup.effectiveOpacity()
+}
+
+// EffectiveParallax returns the parallax factors of the image layer, multiplied by those of all groups containing it.
+func (im *ImageLayer) EffectiveParallax() pixel.Vec {
+	return pixel.V(im.ParallaxX, im.ParallaxY).ScaledXY(im.parentGroup.effectiveParallax())
+}
+
+// EffectiveTint returns the tint colour of the image layer, multiplied by the tint of all groups containing it.
+func (im *ImageLayer) EffectiveTint() pixel.RGBA {
+	return tintMask(im.tint).Mul(im.parentGroup.effectiveTint())
+}
+
+// EffectiveVisible returns whether the image layer is visible; it is hidden if any group containing it is hidden.
+func (im *ImageLayer) EffectiveVisible() bool {
+	return im.Visible && im.parentGroup.effectiveVisible()
+}
+
+// SetOpacity sets the opacity of the image layer, from 0 for transparent to 1 for opaque.
+func (im *ImageLayer) SetOpacity(opacity float64) {
+	im.Opacity = opacity
+}
+
+// SetTintColor sets the tint colour of the image layer, which is multiplied with the colour of the image.  A nil
+// colour removes the tint.
+func (im *ImageLayer) SetTintColor(c color.Color) {
+	im.TintColor = formatColor(c)
+	im.tint = parseTint(im.TintColor)
+}
+
+// SetVisible sets whether the image layer is drawn.
+func (im *ImageLayer) SetVisible(visible bool) {
+	im.Visible = visible
+}
+
 func (im *ImageLayer) String() string {
 	return fmt.Sprintf("ImageLayer{Name: '%s', Image: %s}", im.Name, im.Image)
 }
 
+// UnmarshalXML implements xml.Unmarshaler, defaulting any attributes Tiled omits.
+func (im *ImageLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type imageLayer ImageLayer
+	var v imageLayer
+	setLayerDefaults(&v.Opacity, &v.Visible, &v.ParallaxX, &v.ParallaxY)
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*im = ImageLayer(v)
+	im.tint = parseTint(im.TintColor)
+	return nil
+}
+
 func (im *ImageLayer) setParent(m *Map) {
 	im.parentMap = m
 
@@ -0,0 +1,636 @@
+package tilepix
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+     _  ___   ___   _  _
+  _ | |/ __| / _ \ | \| |
+ | || |\__ \| (_) || .` |
+  \__/ |___/ \___/ |_|\_|
+*/
+
+// The types in this file mirror the Tiled JSON map format.  Once decoded they are converted into the TMX structures,
+// so that the rest of the package need not know which format the map was read from.
+
+// jsonString is a string which may be encoded in JSON as either a string or a number; Tiled has used both for the
+// map version.
+type jsonString string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *jsonString) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*s = jsonString(str)
+		return nil
+	}
+
+	*s = jsonString(bytes.TrimSpace(b))
+	return nil
+}
+
+type jsonChunk struct {
+	X      int             `json:"x"`
+	Y      int             `json:"y"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type jsonFrame struct {
+	TileID   ID  `json:"tileid"`
+	Duration int `json:"duration"`
+}
+
+type jsonLayer struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Opacity    float64         `json:"opacity"`
+	Visible    bool            `json:"visible"`
+	Locked     bool            `json:"locked"`
+	OffSetX    float64         `json:"offsetx"`
+	OffSetY    float64         `json:"offsety"`
+	TintColor  string          `json:"tintcolor"`
+	ParallaxX  float64         `json:"parallaxx"`
+	ParallaxY  float64         `json:"parallaxy"`
+	Properties []*jsonProperty `json:"properties"`
+
+	// Used by tile layers.
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      []*jsonChunk    `json:"chunks"`
+
+	// Used by object groups.
+	Color   string        `json:"color"`
+	Objects []*jsonObject `json:"objects"`
+
+	// Used by image layers.
+	Image            string `json:"image"`
+	ImageWidth       int    `json:"imagewidth"`
+	ImageHeight      int    `json:"imageheight"`
+	TransparentColor string `json:"transparentcolor"`
+
+	// Used by groups.
+	Layers []*jsonLayer `json:"layers"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, defaulting any keys Tiled omits.
+func (jl *jsonLayer) UnmarshalJSON(b []byte) error {
+	type layer jsonLayer
+	var v layer
+	setLayerDefaults(&v.Opacity, &v.Visible, &v.ParallaxX, &v.ParallaxY)
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*jl = jsonLayer(v)
+	return nil
+}
+
+type jsonMap struct {
+	Version         jsonString      `json:"version"`
+	Orientation     string          `json:"orientation"`
+	StaggerAxis     string          `json:"staggeraxis"`
+	StaggerIndex    string          `json:"staggerindex"`
+	HexSideLength   int             `json:"hexsidelength"`
+	RenderOrder     string          `json:"renderorder"`
+	ParallaxOriginX float64         `json:"parallaxoriginx"`
+	ParallaxOriginY float64         `json:"parallaxoriginy"`
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+	TileWidth       int             `json:"tilewidth"`
+	TileHeight      int             `json:"tileheight"`
+	Infinite        bool            `json:"infinite"`
+	BackgroundColor string          `json:"backgroundcolor"`
+	Properties      []*jsonProperty `json:"properties"`
+	Tilesets        []*jsonTileset  `json:"tilesets"`
+	Layers          []*jsonLayer    `json:"layers"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, so that keys which Tiled omits when set to their default are initialised
+// correctly.
+func (jm *jsonMap) UnmarshalJSON(b []byte) error {
+	type tiledMap jsonMap
+	v := tiledMap{RenderOrder: "right-down"}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*jm = jsonMap(v)
+	return nil
+}
+
+type jsonObject struct {
+	ID         ID              `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Class      string          `json:"class"`
+	X          float64         `json:"x"`
+	Y          float64         `json:"y"`
+	Width      float64         `json:"width"`
+	Height     float64         `json:"height"`
+	Rotation   float64         `json:"rotation"`
+	GID        GID             `json:"gid"`
+	Visible    bool            `json:"visible"`
+	Ellipse    bool            `json:"ellipse"`
+	Point      bool            `json:"point"`
+	Polygon    []jsonPoint     `json:"polygon"`
+	PolyLine   []jsonPoint     `json:"polyline"`
+	Properties []*jsonProperty `json:"properties"`
+	Template   string          `json:"template"`
+	Text       *jsonText       `json:"text"`
+
+	// keys holds the keys set on a template instance, named as the attributes of TMX objects.
+	keys map[string]bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler, so that the keys set on template instances are recorded.
+func (jo *jsonObject) UnmarshalJSON(b []byte) error {
+	type object jsonObject
+	v := object{Visible: true}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*jo = jsonObject(v)
+	if jo.Template == "" {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	jo.keys = make(map[string]bool, len(fields))
+	for key := range fields {
+		if key == "class" {
+			// Tiled 1.9 named the object type "class".
+			key = "type"
+		}
+		jo.keys[key] = true
+	}
+	return nil
+}
+
+type jsonPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type jsonProperty struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	PropertyType string          `json:"propertytype"`
+	Value        json.RawMessage `json:"value"`
+}
+
+type jsonText struct {
+	Text       string `json:"text"`
+	FontFamily string `json:"fontfamily"`
+	PixelSize  int    `json:"pixelsize"`
+	Wrap       bool   `json:"wrap"`
+	Color      string `json:"color"`
+	Bold       bool   `json:"bold"`
+	Italic     bool   `json:"italic"`
+	Underline  bool   `json:"underline"`
+	Strikeout  bool   `json:"strikeout"`
+	Kerning    bool   `json:"kerning"`
+	HAlign     string `json:"halign"`
+	VAlign     string `json:"valign"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, so that keys which Tiled omits when set to their default are initialised
+// correctly.
+func (jt *jsonText) UnmarshalJSON(b []byte) error {
+	type text jsonText
+	v := text{FontFamily: "sans-serif", PixelSize: 16, Color: "#000000", Kerning: true, HAlign: "left", VAlign: "top"}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*jt = jsonText(v)
+	return nil
+}
+
+type jsonTile struct {
+	ID          ID              `json:"id"`
+	Type        string          `json:"type"`
+	Class       string          `json:"class"`
+	Probability *float64        `json:"probability"`
+	Properties  []*jsonProperty `json:"properties"`
+	Image       string          `json:"image"`
+	ImageWidth  int             `json:"imagewidth"`
+	ImageHeight int             `json:"imageheight"`
+	ObjectGroup *jsonLayer      `json:"objectgroup"`
+	Animation   []jsonFrame     `json:"animation"`
+}
+
+type jsonTileset struct {
+	FirstGID         GID             `json:"firstgid"`
+	Source           string          `json:"source"`
+	Name             string          `json:"name"`
+	TileWidth        int             `json:"tilewidth"`
+	TileHeight       int             `json:"tileheight"`
+	Spacing          int             `json:"spacing"`
+	Margin           int             `json:"margin"`
+	Tilecount        int             `json:"tilecount"`
+	Columns          int             `json:"columns"`
+	Image            string          `json:"image"`
+	ImageWidth       int             `json:"imagewidth"`
+	ImageHeight      int             `json:"imageheight"`
+	TransparentColor string          `json:"transparentcolor"`
+	Properties       []*jsonProperty `json:"properties"`
+	Tiles            []*jsonTile     `json:"tiles"`
+	TileOffset       *TileOffset     `json:"tileoffset"`
+	Grid             *Grid           `json:"grid"`
+	ObjectAlignment  string          `json:"objectalignment"`
+}
+
+// decodeJSONData decodes JSON layer data, which is either a base64 string or an array of GIDs.  Arrays of GIDs are
+// returned as DataTiles, so that they are decoded in the same way as XML encoded data.
+func decodeJSONData(raw json.RawMessage) (rawData []byte, dataTiles []*DataTile, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil, nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			log.WithError(err).Error("decodeJSONData: could not decode data string")
+			return nil, nil, err
+		}
+		return []byte(s), nil, nil
+	}
+
+	var gids []GID
+	if err := json.Unmarshal(raw, &gids); err != nil {
+		log.WithError(err).Error("decodeJSONData: could not decode data array")
+		return nil, nil, err
+	}
+
+	dataTiles = make([]*DataTile, len(gids))
+	for i, gid := range gids {
+		dataTiles[i] = &DataTile{GID: gid}
+	}
+
+	return nil, dataTiles, nil
+}
+
+func formatJSONPoints(points []jsonPoint) string {
+	pointStrings := make([]string, len(points))
+	for i, p := range points {
+		pointStrings[i] = strconv.FormatFloat(p.X, 'f', -1, 64) + "," + strconv.FormatFloat(p.Y, 'f', -1, 64)
+	}
+
+	return strings.Join(pointStrings, " ")
+}
+
+// inferPropertyType returns the property type which best matches the JSON value provided.
+func inferPropertyType(raw json.RawMessage) PropertyType {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return StringProp
+	}
+
+	switch raw[0] {
+	case '{':
+		return ClassProp
+	case 't', 'f':
+		return BoolProp
+	case '"':
+		return StringProp
+	}
+
+	if bytes.ContainsAny(raw, ".eE") {
+		return FloatProp
+	}
+	return IntProp
+}
+
+// toClassMembers converts the value of a class property, an object of member names to values, into properties sorted
+// by name.  The JSON format does not record the type of members, nor the custom type of nested classes, so the type
+// is inferred from the value; colour and file members are read as strings, and object members as ints.
+func toClassMembers(raw json.RawMessage) Properties {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		log.WithError(err).Warn("toClassMembers: could not decode class property value")
+		return nil
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var props Properties
+	for _, name := range names {
+		jp := &jsonProperty{Name: name, Type: string(inferPropertyType(members[name])), Value: members[name]}
+		props = append(props, jp.toProperty())
+	}
+
+	return props
+}
+
+func toProperties(jps []*jsonProperty) Properties {
+	var props Properties
+	for _, jp := range jps {
+		props = append(props, jp.toProperty())
+	}
+
+	return props
+}
+
+// toGroupLayer converts a group, and all layers within it.  This is also used for the top-level layers of the map.
+func (jl *jsonLayer) toGroupLayer() (*GroupLayer, error) {
+	g := &GroupLayer{
+		Name:       jl.Name,
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		tint:       parseTint(jl.TintColor),
+		ParallaxX:  jl.ParallaxX,
+		ParallaxY:  jl.ParallaxY,
+		Properties: toProperties(jl.Properties),
+	}
+
+	for _, child := range jl.Layers {
+		switch child.Type {
+		case "tilelayer":
+			l, err := child.toTileLayer()
+			if err != nil {
+				log.WithError(err).Error("jsonLayer.toGroupLayer: could not convert tile layer")
+				return nil, err
+			}
+			g.TileLayers = append(g.TileLayers, l)
+			g.Layers = append(g.Layers, l)
+		case "objectgroup":
+			og := child.toObjectGroup()
+			g.ObjectGroups = append(g.ObjectGroups, og)
+			g.Layers = append(g.Layers, og)
+		case "imagelayer":
+			il := child.toImageLayer()
+			g.ImageLayers = append(g.ImageLayers, il)
+			g.Layers = append(g.Layers, il)
+		case "group":
+			cg, err := child.toGroupLayer()
+			if err != nil {
+				log.WithError(err).Error("jsonLayer.toGroupLayer: could not convert group")
+				return nil, err
+			}
+			g.Groups = append(g.Groups, cg)
+			g.Layers = append(g.Layers, cg)
+		default:
+			log.WithField("Type", child.Type).Warn("jsonLayer.toGroupLayer: skipping unsupported layer type")
+		}
+	}
+
+	return g, nil
+}
+
+func (jl *jsonLayer) toImageLayer() *ImageLayer {
+	return &ImageLayer{
+		Locked:    jl.Locked,
+		Name:      jl.Name,
+		OffSetX:   jl.OffSetX,
+		OffSetY:   jl.OffSetY,
+		Opacity:   jl.Opacity,
+		Visible:   jl.Visible,
+		TintColor: jl.TintColor,
+		tint:      parseTint(jl.TintColor),
+		ParallaxX: jl.ParallaxX,
+		ParallaxY: jl.ParallaxY,
+		Image: &Image{
+			Source: jl.Image,
+			Trans:  strings.TrimPrefix(jl.TransparentColor, "#"),
+			Width:  jl.ImageWidth,
+			Height: jl.ImageHeight,
+		},
+	}
+}
+
+func (jl *jsonLayer) toObjectGroup() *ObjectGroup {
+	og := &ObjectGroup{
+		Name:       jl.Name,
+		Color:      jl.Color,
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Opacity:    float32(jl.Opacity),
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		tint:       parseTint(jl.TintColor),
+		ParallaxX:  jl.ParallaxX,
+		ParallaxY:  jl.ParallaxY,
+		Properties: toProperties(jl.Properties),
+	}
+
+	for _, jo := range jl.Objects {
+		og.Objects = append(og.Objects, jo.toObject())
+	}
+
+	return og
+}
+
+func (jl *jsonLayer) toTileLayer() (*TileLayer, error) {
+	// Layers encoded as arrays of GIDs use the XML "encoding" of TMX files.
+	data := Data{Compression: jl.Compression}
+	if jl.Encoding == "base64" {
+		data.Encoding = jl.Encoding
+	}
+
+	var err error
+	data.RawData, data.DataTiles, err = decodeJSONData(jl.Data)
+	if err != nil {
+		log.WithError(err).WithField("Layer", jl.Name).Error("jsonLayer.toTileLayer: could not decode layer data")
+		return nil, err
+	}
+
+	for _, jc := range jl.Chunks {
+		c := &Chunk{X: jc.X, Y: jc.Y, Width: jc.Width, Height: jc.Height}
+		c.RawData, c.DataTiles, err = decodeJSONData(jc.Data)
+		if err != nil {
+			log.WithError(err).WithField("Layer", jl.Name).Error("jsonLayer.toTileLayer: could not decode chunk data")
+			return nil, err
+		}
+
+		data.Chunks = append(data.Chunks, c)
+	}
+
+	return &TileLayer{
+		Name:       jl.Name,
+		Opacity:    float32(jl.Opacity),
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		tint:       parseTint(jl.TintColor),
+		ParallaxX:  jl.ParallaxX,
+		ParallaxY:  jl.ParallaxY,
+		Properties: toProperties(jl.Properties),
+		Data:       data,
+	}, nil
+}
+
+func (jm *jsonMap) toMap() (*Map, error) {
+	m := &Map{
+		Version:         string(jm.Version),
+		Orientation:     jm.Orientation,
+		StaggerAxis:     jm.StaggerAxis,
+		StaggerIndex:    jm.StaggerIndex,
+		HexSideLength:   jm.HexSideLength,
+		RenderOrder:     jm.RenderOrder,
+		ParallaxOriginX: jm.ParallaxOriginX,
+		ParallaxOriginY: jm.ParallaxOriginY,
+		Width:           jm.Width,
+		Height:          jm.Height,
+		TileWidth:       jm.TileWidth,
+		TileHeight:      jm.TileHeight,
+		Properties:      toProperties(jm.Properties),
+		Infinite:        jm.Infinite,
+		BackgroundColor: jm.BackgroundColor,
+	}
+
+	for _, jt := range jm.Tilesets {
+		m.Tilesets = append(m.Tilesets, jt.toTileset())
+	}
+
+	// The map holds its top-level layers in the same way as a group.
+	root, err := (&jsonLayer{Layers: jm.Layers}).toGroupLayer()
+	if err != nil {
+		log.WithError(err).Error("jsonMap.toMap: could not convert layers")
+		return nil, err
+	}
+	m.TileLayers, m.ObjectGroups, m.ImageLayers, m.Groups = root.TileLayers, root.ObjectGroups, root.ImageLayers, root.Groups
+	m.Layers = root.Layers
+
+	return m, nil
+}
+
+func (jo *jsonObject) toObject() *Object {
+	o := &Object{
+		Name:       jo.Name,
+		Type:       jo.Type,
+		X:          jo.X,
+		Y:          jo.Y,
+		Width:      jo.Width,
+		Height:     jo.Height,
+		Rotation:   jo.Rotation,
+		GID:        jo.GID,
+		ID:         jo.ID,
+		Visible:    jo.Visible,
+		Properties: toProperties(jo.Properties),
+		Template:   jo.Template,
+		attrs:      jo.keys,
+	}
+
+	if o.Type == "" {
+		// Tiled 1.9 named the object type "class".
+		o.Type = jo.Class
+	}
+	if jo.Ellipse {
+		o.Ellipse = &struct{}{}
+	}
+	if jo.Point {
+		o.Point = &struct{}{}
+	}
+	if jo.Polygon != nil {
+		o.Polygon = &Polygon{Points: formatJSONPoints(jo.Polygon)}
+	}
+	if jo.PolyLine != nil {
+		o.PolyLine = &PolyLine{Points: formatJSONPoints(jo.PolyLine)}
+	}
+	if jo.Text != nil {
+		t := Text(*jo.Text)
+		o.Text = &t
+	}
+
+	return o
+}
+
+func (jp *jsonProperty) toProperty() *Property {
+	p := &Property{Name: jp.Name, Type: PropertyType(jp.Type), PropertyType: jp.PropertyType}
+	if p.Type == "" {
+		p.Type = StringProp
+	}
+
+	if p.Type == ClassProp {
+		p.Properties = toClassMembers(jp.Value)
+		return p
+	}
+
+	// Strings are unquoted; all other types keep their JSON representation, which matches their TMX attribute.
+	p.Value = string(bytes.TrimSpace(jp.Value))
+	var s string
+	if err := json.Unmarshal(jp.Value, &s); err == nil {
+		p.Value = s
+	}
+
+	return p
+}
+
+func (jt *jsonTile) toTile() *Tile {
+	t := &Tile{ID: jt.ID, Type: jt.Type, Probability: 1, Properties: toProperties(jt.Properties)}
+	if t.Type == "" {
+		// Tiled 1.9 named the tile type "class".
+		t.Type = jt.Class
+	}
+	if jt.Probability != nil {
+		t.Probability = *jt.Probability
+	}
+
+	if jt.Image != "" {
+		t.Image = &Image{Source: jt.Image, Width: jt.ImageWidth, Height: jt.ImageHeight}
+	}
+	if jt.ObjectGroup != nil {
+		t.ObjectGroup = jt.ObjectGroup.toObjectGroup()
+	}
+	for _, f := range jt.Animation {
+		t.Animation = append(t.Animation, &Frame{TileID: f.TileID, Duration: f.Duration})
+	}
+
+	return t
+}
+
+func (jt *jsonTileset) toTileset() *Tileset {
+	ts := &Tileset{
+		FirstGID:        jt.FirstGID,
+		Source:          jt.Source,
+		Name:            jt.Name,
+		TileWidth:       jt.TileWidth,
+		TileHeight:      jt.TileHeight,
+		Spacing:         jt.Spacing,
+		Margin:          jt.Margin,
+		Properties:      toProperties(jt.Properties),
+		Tilecount:       jt.Tilecount,
+		Columns:         jt.Columns,
+		TileOffset:      jt.TileOffset,
+		Grid:            jt.Grid,
+		ObjectAlignment: jt.ObjectAlignment,
+	}
+
+	if jt.Image != "" {
+		ts.Image = &Image{
+			Source: jt.Image,
+			Trans:  strings.TrimPrefix(jt.TransparentColor, "#"),
+			Width:  jt.ImageWidth,
+			Height: jt.ImageHeight,
+		}
+	}
+	for _, t := range jt.Tiles {
+		ts.Tiles = append(ts.Tiles, t.toTile())
+	}
+
+	return ts
+}
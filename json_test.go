@@ -0,0 +1,197 @@
+package tilepix_test
+
+import (
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+	"github.com/gopxl/pixel"
+)
+
+func TestReadFileJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmx     string
+		tmj     string
+		wantErr bool
+	}{
+		{
+			name: "base64-zlib and polygons",
+			tmx:  "testdata/poly.tmx",
+			tmj:  "testdata/poly.tmj",
+		},
+		{
+			name: "csv",
+			tmx:  "testdata/csv.tmx",
+			tmj:  "testdata/csv.tmj",
+		},
+		{
+			name: "external tileset",
+			tmx:  "testdata/external_tileset.tmx",
+			tmj:  "testdata/external_tileset.tmj",
+		},
+		{
+			name: "external JSON tileset",
+			tmx:  "testdata/external_tileset_json.tmx",
+			tmj:  "testdata/external_tileset_json.tmj",
+		},
+		{
+			name: "infinite csv",
+			tmx:  "testdata/infinite-csv.tmx",
+			tmj:  "testdata/infinite-csv.tmj",
+		},
+		{
+			name: "infinite base64-zlib",
+			tmx:  "testdata/infinite-zlib.tmx",
+			tmj:  "testdata/infinite-zlib.tmj",
+		},
+		{
+			name:    "missing file",
+			tmj:     "testdata/foo.tmj",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tilepix.ReadFileJSON(tt.tmj)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tilepix.ReadFileJSON(): error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want, err := tilepix.ReadFile(tt.tmx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.TileLayers) != len(want.TileLayers) {
+				t.Fatalf("Got %d tile layers, want %d", len(got.TileLayers), len(want.TileLayers))
+			}
+			for i, l := range got.TileLayers {
+				wantLayer := want.TileLayers[i]
+				if len(l.DecodedTiles) != len(wantLayer.DecodedTiles) {
+					t.Fatalf("Got %d tiles, want %d", len(l.DecodedTiles), len(wantLayer.DecodedTiles))
+				}
+				for j, tile := range l.DecodedTiles {
+					if tile.ID != wantLayer.DecodedTiles[j].ID || tile.Nil != wantLayer.DecodedTiles[j].Nil {
+						t.Fatalf("Tile %d: got %v, want %v", j, tile, wantLayer.DecodedTiles[j])
+					}
+				}
+			}
+
+			if len(got.ObjectGroups) != len(want.ObjectGroups) {
+				t.Fatalf("Got %d object groups, want %d", len(got.ObjectGroups), len(want.ObjectGroups))
+			}
+			for i, og := range got.ObjectGroups {
+				for j, o := range og.Objects {
+					wantObj := want.ObjectGroups[i].Objects[j]
+					if o.GetType() != wantObj.GetType() || o.X != wantObj.X || o.Y != wantObj.Y {
+						t.Errorf("Object %d: got %v at %v,%v, want %v at %v,%v", j, o, o.X, o.Y, wantObj, wantObj.X, wantObj.Y)
+					}
+					if len(o.Properties) != len(wantObj.Properties) {
+						t.Fatalf("Object %d: got properties %v, want %v", j, o.Properties, wantObj.Properties)
+					}
+					for k, p := range o.Properties {
+						if p.String() != wantObj.Properties[k].String() {
+							t.Errorf("Object %d: got property %v, want %v", j, p, wantObj.Properties[k])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadFileJSON_Objects(t *testing.T) {
+	// ReadFile should choose the JSON reader from the file extension.
+	m, err := tilepix.ReadFile("testdata/objects.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objs := m.GetObjectLayerByName("Object Layer 1").Objects
+	if len(objs) != 3 {
+		t.Fatalf("Expected 3 objects, got %d", len(objs))
+	}
+
+	ellipse, err := objs[0].GetEllipse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pixel.C(pixel.V(50, 150), 100); ellipse != want {
+		t.Errorf("Object.GetEllipse() = %v, want %v", ellipse, want)
+	}
+
+	point, err := objs[1].GetPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pixel.V(160, 160); point != want {
+		t.Errorf("Object.GetPoint() = %v, want %v", point, want)
+	}
+
+	rect, err := objs[2].GetRect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pixel.R(0, 0, 100, 100); rect != want {
+		t.Errorf("Object.GetRect() = %v, want %v", rect, want)
+	}
+
+	il := m.GetImageLayerByName("Image Layer 1")
+	if il == nil || il.Image.Source != "logo_small.png" || il.OffSetX != 4 || il.OffSetY != 8 {
+		t.Errorf("Unexpected image layer %v", il)
+	}
+}
+
+func TestReadFileJSON_FractionalPoints(t *testing.T) {
+	got, err := tilepix.ReadFile("testdata/poly_fractional.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := tilepix.ReadFile("testdata/poly_fractional.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotObjs := got.GetObjectLayerByName("Object Layer 1").Objects
+	wantObjs := want.GetObjectLayerByName("Object Layer 1").Objects
+
+	gotPolygon, err := gotObjs[0].GetPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPolygon, err := wantObjs[0].GetPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotPolygon) != 3 || len(wantPolygon) != 3 {
+		t.Fatalf("Got %d and %d polygon points, want 3", len(gotPolygon), len(wantPolygon))
+	}
+	for i, p := range gotPolygon {
+		if p != wantPolygon[i] {
+			t.Errorf("Polygon point %d: got %v, want %v", i, p, wantPolygon[i])
+		}
+	}
+	if want := pixel.V(2.5, 256-91.25); gotPolygon[1] != want {
+		t.Errorf("Polygon point 1: got %v, want %v", gotPolygon[1], want)
+	}
+
+	gotPolyLine, err := gotObjs[1].GetPolyLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPolyLine, err := wantObjs[1].GetPolyLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotPolyLine) != 5 || len(wantPolyLine) != 5 {
+		t.Fatalf("Got %d and %d polyline points, want 5", len(gotPolyLine), len(wantPolyLine))
+	}
+	for i, p := range gotPolyLine {
+		if p != wantPolyLine[i] {
+			t.Errorf("Polyline point %d: got %v, want %v", i, p, wantPolyLine[i])
+		}
+	}
+}
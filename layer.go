@@ -0,0 +1,167 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"image/color"
+	"io"
+
+	"github.com/gopxl/pixel"
+)
+
+/*
+  _
+ | |    __ _  _  _  ___  _ _
+ | |__ / _` || || |/ -_)| '_|
+ |____|\__,_| \_, |\___||_|
+              |__/
+*/
+
+// Layer is implemented by each kind of layer; *TileLayer, *ObjectGroup, *ImageLayer and *GroupLayer.  The Layers
+// fields of Map and GroupLayer hold their layers in the order they appear in Tiled, bottom-most first; use a type
+// switch to access the layer itself.
+type Layer interface {
+	EffectiveOffset() pixel.Vec
+	EffectiveOpacity() float64
+	EffectiveParallax() pixel.Vec
+	EffectiveTint() pixel.RGBA
+	EffectiveVisible() bool
+	SetOpacity(opacity float64)
+	SetTintColor(c color.Color)
+	SetVisible(visible bool)
+	String() string
+}
+
+// colorMask returns the colour mask a layer is drawn with; its effective tint, faded by its effective opacity.
+func colorMask(l Layer) pixel.RGBA {
+	return l.EffectiveTint().Mul(pixel.Alpha(l.EffectiveOpacity()))
+}
+
+// setLayerDefaults sets the attributes shared by every kind of layer to the defaults Tiled uses when it omits them.
+// Opacity is generic, as it is a float32 for some kinds of layer.
+func setLayerDefaults[O float32 | float64](opacity *O, visible *bool, parallaxX, parallaxY *float64) {
+	*opacity, *visible, *parallaxX, *parallaxY = 1, true, 1, 1
+}
+
+// orderLayers returns the layers provided as a single slice, in the order given by the element names in order.  Any
+// layers not covered by order are appended in the order tile layers, object groups, image layers then groups.
+func orderLayers(order []string, tls []*TileLayer, ogs []*ObjectGroup, ils []*ImageLayer, gls []*GroupLayer) []Layer {
+	layers := make([]Layer, 0, len(tls)+len(ogs)+len(ils)+len(gls))
+
+	for _, name := range order {
+		switch {
+		case name == "layer" && len(tls) > 0:
+			layers, tls = append(layers, tls[0]), tls[1:]
+		case name == "objectgroup" && len(ogs) > 0:
+			layers, ogs = append(layers, ogs[0]), ogs[1:]
+		case name == "imagelayer" && len(ils) > 0:
+			layers, ils = append(layers, ils[0]), ils[1:]
+		case name == "group" && len(gls) > 0:
+			layers, gls = append(layers, gls[0]), gls[1:]
+		}
+	}
+
+	for _, l := range tls {
+		layers = append(layers, l)
+	}
+	for _, og := range ogs {
+		layers = append(layers, og)
+	}
+	for _, il := range ils {
+		layers = append(layers, il)
+	}
+	for _, g := range gls {
+		layers = append(layers, g)
+	}
+
+	return layers
+}
+
+// layerList holds the layers directly within a map or group, as they are decoded, along with their order.
+type layerList struct {
+	tileLayers   []*TileLayer
+	objectGroups []*ObjectGroup
+	imageLayers  []*ImageLayer
+	groups       []*GroupLayer
+	order        []string
+}
+
+// decode decodes the element started by start if it is a layer, reporting whether it was.
+func (ll *layerList) decode(d *xml.Decoder, start xml.StartElement) (bool, error) {
+	var err error
+	switch start.Name.Local {
+	case "layer":
+		l := new(TileLayer)
+		err = d.DecodeElement(l, &start)
+		ll.tileLayers = append(ll.tileLayers, l)
+	case "objectgroup":
+		og := new(ObjectGroup)
+		err = d.DecodeElement(og, &start)
+		ll.objectGroups = append(ll.objectGroups, og)
+	case "imagelayer":
+		il := new(ImageLayer)
+		err = d.DecodeElement(il, &start)
+		ll.imageLayers = append(ll.imageLayers, il)
+	case "group":
+		g := new(GroupLayer)
+		err = d.DecodeElement(g, &start)
+		ll.groups = append(ll.groups, g)
+	default:
+		return false, nil
+	}
+
+	ll.order = append(ll.order, start.Name.Local)
+	return true, err
+}
+
+// layers returns the layers decoded, in the order they appeared.
+func (ll *layerList) layers() []Layer {
+	return orderLayers(ll.order, ll.tileLayers, ll.objectGroups, ll.imageLayers, ll.groups)
+}
+
+// decodeAttrs decodes the attributes of the element started by start into v, without reading any of its children.
+func decodeAttrs(start xml.StartElement, v interface{}) error {
+	return xml.NewTokenDecoder(&tokenList{start, start.End()}).Decode(v)
+}
+
+// decodeChildren calls decode with each element directly within the element being read by d, until it ends.  decode
+// must read the whole of the element it is given.
+func decodeChildren(d *xml.Decoder, decode func(start xml.StartElement) error) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := decode(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeProperties decodes the properties element started by start.
+func decodeProperties(d *xml.Decoder, start xml.StartElement) (Properties, error) {
+	var ps struct {
+		Properties Properties `xml:"property"`
+	}
+	err := d.DecodeElement(&ps, &start)
+	return ps.Properties, err
+}
+
+// tokenList is an xml.TokenReader which returns the tokens it holds.
+type tokenList []xml.Token
+
+// Token implements xml.TokenReader.
+func (l *tokenList) Token() (xml.Token, error) {
+	if len(*l) == 0 {
+		return nil, io.EOF
+	}
+
+	tok := (*l)[0]
+	*l = (*l)[1:]
+	return tok, nil
+}
@@ -1,8 +1,12 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
 	"image/color"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gopxl/pixel"
 	"github.com/gopxl/pixel/pixelgl"
@@ -19,53 +23,83 @@ import (
 
 // Map is a TMX file structure representing the map as a whole.
 type Map struct {
-	Version     string `xml:"title,attr"`
+	Version string `xml:"title,attr"`
+	// Orientation is one of "orthogonal", "isometric", "staggered" or "hexagonal".
 	Orientation string `xml:"orientation,attr"`
+	// StaggerAxis is "x" or "y", and StaggerIndex "odd" or "even"; these give which columns or rows are shifted on
+	// staggered and hexagonal maps.
+	StaggerAxis  string `xml:"staggeraxis,attr"`
+	StaggerIndex string `xml:"staggerindex,attr"`
+	// HexSideLength is the length in pixels of the flat sides of the tiles of hexagonal maps.
+	HexSideLength int `xml:"hexsidelength,attr"`
+	// RenderOrder is the order tiles are drawn on orthogonal maps; one of "right-down", "right-up", "left-down" or
+	// "left-up".  Tiles are drawn row by row, the direction along the rows given first.
+	RenderOrder string `xml:"renderorder,attr"`
+	// ParallaxOriginX and ParallaxOriginY are the position, in pixels from the top-left of the map, at which the camera
+	// must be centred for layers to be drawn without a parallax offset.
+	ParallaxOriginX float64 `xml:"parallaxoriginx,attr"`
+	ParallaxOriginY float64 `xml:"parallaxoriginy,attr"`
 	// Width is the number of tiles - not the width in pixels
 	Width int `xml:"width,attr"`
 	// Height is the number of tiles - not the height in pixels
 	Height          int            `xml:"height,attr"`
 	TileWidth       int            `xml:"tilewidth,attr"`
 	TileHeight      int            `xml:"tileheight,attr"`
-	Properties      []*Property    `xml:"properties>property"`
+	Properties      Properties     `xml:"properties>property"`
 	Tilesets        []*Tileset     `xml:"tileset"`
 	TileLayers      []*TileLayer   `xml:"layer"`
 	ObjectGroups    []*ObjectGroup `xml:"objectgroup"`
 	Infinite        bool           `xml:"infinite,attr"`
 	ImageLayers     []*ImageLayer  `xml:"imagelayer"`
+	Groups          []*GroupLayer  `xml:"group"`
 	BackgroundColor string         `xml:"backgroundcolor,attr"`
+	// Layers holds all top-level layers of the map in the order they appear in Tiled, bottom-most first.
+	Layers []Layer `xml:"-"`
 
 	canvas *pixelgl.Canvas
 	// dir is the directory the tmx file is located in.  This is used to access images for tilesets via a relative path.
 	dir string
+	// openFileFunc is the function used to open files the map refers to, such as tilesets and object templates.
+	openFileFunc func(name string) (http.File, error)
+	// templates caches the objects of templates which have been read, by their path.
+	templates map[string]*Object
+	// clock is the time used to animate tiles, it is advanced with Update.
+	clock time.Duration
 }
 
-// DrawAll will draw all tile layers and image layers to the target.
+// DrawAll will draw all tile layers and image layers to the target, including those within groups, in the order they
+// appear in Tiled.
 // Tile layers are first draw to their own `pixel.Batch`s for efficiency.
 // All layers are drawn to a `pixel.Canvas` before being drawn to the target.
+// Layers are drawn with their effective opacity and tint, and hidden layers are skipped.
+// Layers are drawn as if the camera were at the parallax origin of the map, so have no parallax offset; see
+// DrawAllParallax.
 //
 // - target - The target to draw layers to.
 // - clearColour - The colour to clear the maps' canvas before drawing.
 // - mat - The matrix to draw the canvas to the target with.
 func (m *Map) DrawAll(target pixel.Target, clearColour color.Color, mat pixel.Matrix) error {
+	return m.DrawAllParallax(target, clearColour, mat, m.parallaxOrigin())
+}
+
+// DrawAllParallax will draw the map to the target in the same way as DrawAll, with each layer offset by its parallax
+// factors relative to the camera; see ParallaxOffset.
+//
+// - target - The target to draw layers to.
+// - clearColour - The colour to clear the maps' canvas before drawing.
+// - mat - The matrix to draw the canvas to the target with.
+// - camera - The game position at the centre of the view.
+func (m *Map) DrawAllParallax(target pixel.Target, clearColour color.Color, mat pixel.Matrix, camera pixel.Vec) error {
 	if m.canvas == nil {
 		m.canvas = pixelgl.NewCanvas(m.Bounds())
 	}
 	m.canvas.Clear(clearColour)
 
-	for _, l := range m.TileLayers {
-		if err := l.Draw(m.canvas); err != nil {
-			log.WithError(err).Error("Map.DrawAll: could not draw layer")
-			return err
-		}
-	}
-
-	for _, il := range m.ImageLayers {
-		// The matrix shift is because images are drawn from the top-left in Tiled.
-		if err := il.Draw(m.canvas, pixel.IM.Moved(pixel.V(0, m.pixelHeight()))); err != nil {
-			log.WithError(err).Error("Map.DrawAll: could not draw image layer")
-			return err
-		}
+	err := m.drawLayers(m.Layers, camera)
+	m.canvas.SetMatrix(pixel.IM)
+	if err != nil {
+		log.WithError(err).Error("Map.DrawAllParallax: could not draw layers")
+		return err
 	}
 
 	m.canvas.Draw(target, mat.Moved(m.Bounds().Center()))
@@ -76,44 +110,87 @@ func (m *Map) DrawAll(target pixel.Target, clearColour color.Color, mat pixel.Ma
 // GenerateTileObjectLayer will create an object layer which contains all objects as defined by individual tiles.
 func (m *Map) GenerateTileObjectLayer() error {
 	for _, ts := range m.Tilesets {
-		objGroup := ts.GenerateTileObjectLayer(m.TileLayers)
+		objGroup := ts.GenerateTileObjectLayer(m.allTileLayers())
 		if err := objGroup.decode(); err != nil {
 			log.WithField("ObjectGroup", objGroup).WithError(err).Error("Map.GenerateTileObjectLayer: could not deccode object group")
 			return err
 		}
 		m.ObjectGroups = append(m.ObjectGroups, &objGroup)
+		m.Layers = append(m.Layers, &objGroup)
 	}
 
 	return nil
 }
 
-// GetImageLayerByName returns a Map's ImageLayer by its name
+// GetGroupLayerByName returns a Map's GroupLayer by its name, searching all groups including those nested within
+// other groups.  The name may also be a path of group names, such as "world/buildings".
+func (m *Map) GetGroupLayerByName(name string) *GroupLayer {
+	for _, g := range m.allGroups() {
+		if g.Name == name {
+			return g
+		}
+	}
+
+	return m.findGroup(strings.Split(name, "/"))
+}
+
+// GetImageLayerByName returns a Map's ImageLayer by its name, searching all layers including those within groups.  The
+// name may also be a path of group names followed by the layer name, such as "world/background".
 func (m *Map) GetImageLayerByName(name string) *ImageLayer {
-	for _, l := range m.ImageLayers {
+	for _, l := range m.allImageLayers() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	groupPath, layerName := splitLayerPath(name)
+	if g := m.findGroup(groupPath); g != nil {
+		for _, l := range g.ImageLayers {
+			if l.Name == layerName {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
-// GetObjectLayerByName returns a Map's ObjectGroup by its name
+// GetObjectLayerByName returns a Map's ObjectGroup by its name, searching all layers including those within groups.
+// The name may also be a path of group names followed by the layer name, such as "world/triggers".
 func (m *Map) GetObjectLayerByName(name string) *ObjectGroup {
-	for _, l := range m.ObjectGroups {
+	for _, l := range m.allObjectGroups() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	groupPath, layerName := splitLayerPath(name)
+	if g := m.findGroup(groupPath); g != nil {
+		for _, l := range g.ObjectGroups {
+			if l.Name == layerName {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
-// GetTileLayerByName returns a Map's TileLayer by its name
+// GetTileLayerByName returns a Map's TileLayer by its name, searching all layers including those within groups.  The
+// name may also be a path of group names followed by the layer name, such as "world/ground".
 func (m *Map) GetTileLayerByName(name string) *TileLayer {
-	for _, l := range m.TileLayers {
+	for _, l := range m.allTileLayers() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	groupPath, layerName := splitLayerPath(name)
+	if g := m.findGroup(groupPath); g != nil {
+		for _, l := range g.TileLayers {
+			if l.Name == layerName {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
@@ -121,12 +198,57 @@ func (m *Map) GetTileLayerByName(name string) *TileLayer {
 func (m *Map) GetObjectByName(name string) []*Object {
 	var objs []*Object
 
-	for _, og := range m.ObjectGroups {
+	for _, og := range m.allObjectGroups() {
 		objs = append(objs, og.GetObjectByName(name)...)
 	}
 	return objs
 }
 
+// GetObjectByID returns the Object with the ID provided, searching all object layers including those within groups.
+// Nil is returned if there is no object with that ID.
+func (m *Map) GetObjectByID(id ID) *Object {
+	for _, og := range m.allObjectGroups() {
+		for _, o := range og.Objects {
+			if o.ID == id {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// ParallaxOffset returns the offset, in game co-ordinates, the layer provided is drawn at when the camera is centred on
+// the game position provided.  As in Tiled, layers are offset by the distance of the camera from the parallax origin
+// of the map scaled by one minus their parallax factor; a layer with a factor of 1 is not offset, and one with a
+// factor of 0 moves with the camera.
+func (m *Map) ParallaxOffset(l Layer, camera pixel.Vec) pixel.Vec {
+	factor := l.EffectiveParallax()
+	return camera.Sub(m.parallaxOrigin()).ScaledXY(pixel.V(1-factor.X, 1-factor.Y))
+}
+
+// Props returns the custom properties of the map.
+func (m *Map) Props() Properties {
+	return m.Properties
+}
+
+// TileToWorld returns the game position of the centre of the tile at the tile co-ordinates provided, where (0,0) is
+// the top-left tile as in Tiled.  The orientation of the map is taken into account.
+func (m *Map) TileToWorld(x, y int) pixel.Vec {
+	return m.tileCell(x, y).Center()
+}
+
+// WorldToTile returns the tile co-ordinates, where (0,0) is the top-left tile as in Tiled, of the tile covering the game
+// position provided.  The orientation of the map is taken into account.  The co-ordinates returned may be outside of
+// the map.
+func (m *Map) WorldToTile(pos pixel.Vec) (x, y int) {
+	return m.worldToTile(pos)
+}
+
+// Update advances the maps' clock, which is used to animate tiles, by dt.
+func (m *Map) Update(dt time.Duration) {
+	m.clock += dt
+}
+
 func (m *Map) String() string {
 	return fmt.Sprintf(
 		"Map{Version: %s, Tile dimensions: %dx%d, Properties: %v, Tilesets: %v, TileLayers: %v, Object layers: %v, Image layers: %v}",
@@ -141,9 +263,32 @@ func (m *Map) String() string {
 	)
 }
 
-// Bounds will return a pixel rectangle representing the width-height in pixels.
+// Bounds will return a pixel rectangle representing the width-height in pixels.  For infinite maps this is the area
+// covered by all tile layers, which may extend into negative co-ordinates.
 func (m *Map) Bounds() pixel.Rect {
-	return pixel.R(0, 0, m.pixelWidth(), m.pixelHeight())
+	if !m.Infinite {
+		return pixel.R(0, 0, m.pixelWidth(), m.pixelHeight())
+	}
+
+	var bounds pixel.Rect
+	for _, l := range m.allTileLayers() {
+		lb := l.Bounds()
+		if lb.Area() == 0 {
+			continue
+		}
+
+		if bounds.Area() == 0 {
+			bounds = lb
+			continue
+		}
+		bounds = bounds.Union(lb)
+	}
+
+	if bounds.Area() == 0 {
+		return pixel.R(0, 0, m.pixelWidth(), m.pixelHeight())
+	}
+
+	return bounds
 }
 
 // Centre will return a pixel vector reprensenting the center of the bounds.
@@ -151,11 +296,151 @@ func (m *Map) Centre() pixel.Vec {
 	return m.Bounds().Center()
 }
 
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the map.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (m *Map) UnmarshalProperties(v interface{}) error {
+	return m.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that the order of the layers in the TMX file is recorded in Layers, and
+// attributes which Tiled omits when set to their default are initialised correctly.
+func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tmxMap Map
+	tm := tmxMap{RenderOrder: "right-down"}
+	if err := decodeAttrs(start, &tm); err != nil {
+		log.WithError(err).Error("Map.UnmarshalXML: could not decode attributes")
+		return err
+	}
+
+	var ll layerList
+	err := decodeChildren(d, func(child xml.StartElement) error {
+		if ok, err := ll.decode(d, child); ok {
+			return err
+		}
+
+		switch child.Name.Local {
+		case "tileset":
+			ts := new(Tileset)
+			tm.Tilesets = append(tm.Tilesets, ts)
+			return d.DecodeElement(ts, &child)
+		case "properties":
+			ps, err := decodeProperties(d, child)
+			tm.Properties = append(tm.Properties, ps...)
+			return err
+		}
+		return d.Skip()
+	})
+	if err != nil {
+		log.WithError(err).Error("Map.UnmarshalXML: could not decode map")
+		return err
+	}
+
+	*m = Map(tm)
+	m.TileLayers, m.ObjectGroups, m.ImageLayers, m.Groups = ll.tileLayers, ll.objectGroups, ll.imageLayers, ll.groups
+	m.Layers = ll.layers()
+	return nil
+}
+
+// allGroups returns every GroupLayer in the map, parents before their children.
+func (m *Map) allGroups() []*GroupLayer {
+	var groups []*GroupLayer
+	for _, g := range m.Groups {
+		groups = append(groups, g.allGroups()...)
+	}
+
+	return groups
+}
+
+// allImageLayers returns every ImageLayer in the map, including those within groups.
+func (m *Map) allImageLayers() []*ImageLayer {
+	layers := append([]*ImageLayer(nil), m.ImageLayers...)
+	for _, g := range m.allGroups() {
+		layers = append(layers, g.ImageLayers...)
+	}
+
+	return layers
+}
+
+// allObjectGroups returns every ObjectGroup in the map, including those within groups.
+func (m *Map) allObjectGroups() []*ObjectGroup {
+	groups := append([]*ObjectGroup(nil), m.ObjectGroups...)
+	for _, g := range m.allGroups() {
+		groups = append(groups, g.ObjectGroups...)
+	}
+
+	return groups
+}
+
+// allTileLayers returns every TileLayer in the map, including those within groups.
+func (m *Map) allTileLayers() []*TileLayer {
+	layers := append([]*TileLayer(nil), m.TileLayers...)
+	for _, g := range m.allGroups() {
+		layers = append(layers, g.TileLayers...)
+	}
+
+	return layers
+}
+
+// findGroup returns the group at the path of group names provided.  An empty path returns nil, as the map itself is
+// not a group.
+func (m *Map) findGroup(path []string) *GroupLayer {
+	if len(path) == 0 {
+		return nil
+	}
+
+	for _, g := range m.Groups {
+		if g.Name == path[0] {
+			if found := g.findGroup(path[1:]); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+// drawLayers draws the tile layers and image layers provided to the maps' canvas, recursing into groups.  Each layer
+// is offset by its parallax offset for the camera provided.
+func (m *Map) drawLayers(layers []Layer, camera pixel.Vec) error {
+	for _, layer := range layers {
+		m.canvas.SetMatrix(pixel.IM.Moved(m.ParallaxOffset(layer, camera)))
+
+		switch l := layer.(type) {
+		case *TileLayer:
+			if err := l.Draw(m.canvas); err != nil {
+				log.WithError(err).Error("Map.drawLayers: could not draw layer")
+				return err
+			}
+		case *ImageLayer:
+			// The matrix shift is because images are drawn from the top-left in Tiled.
+			if err := l.Draw(m.canvas, pixel.IM.Moved(pixel.V(0, m.pixelHeight()))); err != nil {
+				log.WithError(err).Error("Map.drawLayers: could not draw image layer")
+				return err
+			}
+		case *GroupLayer:
+			// Nothing within a hidden group is drawn.
+			if !l.EffectiveVisible() {
+				continue
+			}
+			if err := m.drawLayers(l.Layers, camera); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parallaxOrigin returns the parallax origin of the map in game co-ordinates.
+func (m *Map) parallaxOrigin() pixel.Vec {
+	return pixel.V(m.ParallaxOriginX, m.pixelHeight()-m.ParallaxOriginY)
+}
+
 func (m *Map) pixelWidth() float64 {
-	return float64(m.Width * m.TileWidth)
+	return m.pixelSize().X
 }
 func (m *Map) pixelHeight() float64 {
-	return float64(m.Height * m.TileHeight)
+	return m.pixelSize().Y
 }
 
 func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
@@ -167,14 +452,20 @@ func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
 
 	for i := len(m.Tilesets) - 1; i >= 0; i-- {
 		if m.Tilesets[i].FirstGID <= gidBare {
-			return &DecodedTile{
+			dt := &DecodedTile{
 				ID:             ID(gidBare - m.Tilesets[i].FirstGID),
 				Tileset:        m.Tilesets[i],
 				HorizontalFlip: gid&gidHorizontalFlip != 0,
 				VerticalFlip:   gid&gidVerticalFlip != 0,
 				DiagonalFlip:   gid&gidDiagonalFlip != 0,
+				RotateHex120:   gid&gidRotateHex120 != 0,
 				Nil:            false,
-			}, nil
+			}
+			if tile := dt.Tileset.GetTile(dt.ID); tile != nil && len(tile.Animation) > 0 {
+				dt.animation = tile.Animation
+				dt.frame = tile.Animation[0].TileID
+			}
+			return dt, nil
 		}
 	}
 
@@ -183,9 +474,15 @@ func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
 }
 
 func (m *Map) decodeLayers() error {
+	// Link layers to their groups first, so that group offsets can be applied when decoding.
+	for _, g := range m.Groups {
+		g.parentGroup = nil
+		g.linkChildren()
+	}
+
 	// Decode tile layers
-	for _, l := range m.TileLayers {
-		gids, err := l.decode(m.Width, m.Height)
+	for _, l := range m.allTileLayers() {
+		gids, err := l.decode(m.Width, m.Height, m.Infinite)
 		if err != nil {
 			log.WithError(err).Error("Map.decodeLayers: could not decode layer")
 			return err
@@ -198,12 +495,18 @@ func (m *Map) decodeLayers() error {
 				log.WithError(err).Error("Map.decodeLayers: could not GID")
 				return err
 			}
+			if !decTile.IsNil() {
+				decTile.parentLayer = l
+			}
+			if decTile.animation != nil {
+				l.animatedTiles = append(l.animatedTiles, decTile)
+			}
 			l.DecodedTiles[j] = decTile
 		}
 	}
 
 	// Decode object layers
-	for _, og := range m.ObjectGroups {
+	for _, og := range m.allObjectGroups() {
 		if err := og.decode(); err != nil {
 			log.WithError(err).Error("Map.decodeLayers: could not decode Object Group")
 			return err
@@ -213,6 +516,19 @@ func (m *Map) decodeLayers() error {
 	return nil
 }
 
+// resetSprites clears the sprites of all tiles and tile objects, so that they are recalculated from the pictures of
+// their tilesets when next drawn.
+func (m *Map) resetSprites() {
+	for _, l := range m.allTileLayers() {
+		l.resetSprites()
+	}
+	for _, og := range m.allObjectGroups() {
+		for _, o := range og.Objects {
+			o.tile = nil
+		}
+	}
+}
+
 func (m *Map) setParents() {
 	for _, p := range m.Properties {
 		p.setParent(m)
@@ -220,13 +536,16 @@ func (m *Map) setParents() {
 	for _, t := range m.Tilesets {
 		t.setParent(m)
 	}
-	for _, og := range m.ObjectGroups {
+	for _, og := range m.allObjectGroups() {
 		og.setParent(m)
 	}
-	for _, im := range m.ImageLayers {
+	for _, im := range m.allImageLayers() {
 		im.setParent(m)
 	}
-	for _, l := range m.TileLayers {
+	for _, l := range m.allTileLayers() {
 		l.setParent(m)
 	}
+	for _, g := range m.allGroups() {
+		g.setParent(m)
+	}
 }
@@ -141,6 +141,57 @@ func TestMap_DrawAll(t *testing.T) {
 	}
 }
 
+func TestMap_Layers(t *testing.T) {
+	want := []string{"background", "ground", "spawns", "decor", "decor/flowers", "decor/overlay", "roof"}
+
+	for _, path := range []string{"testdata/layer_order.tmx", "testdata/layer_order.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := layerNames("", m.Layers)
+			if len(got) != len(want) {
+				t.Fatalf("Map.Layers = %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("Map.Layers = %v, want %v", got, want)
+				}
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Fatalf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
+
+// layerNames returns the names of the layers provided in order, prefixing layers within groups with the group path.
+func layerNames(prefix string, layers []tilepix.Layer) []string {
+	var names []string
+	for _, layer := range layers {
+		switch l := layer.(type) {
+		case *tilepix.TileLayer:
+			names = append(names, prefix+l.Name)
+		case *tilepix.ObjectGroup:
+			names = append(names, prefix+l.Name)
+		case *tilepix.ImageLayer:
+			names = append(names, prefix+l.Name)
+		case *tilepix.GroupLayer:
+			names = append(names, prefix+l.Name)
+			names = append(names, layerNames(prefix+l.Name+"/", l.Layers)...)
+		}
+	}
+
+	return names
+}
+
 func BenchmarkMap_DrawAll(b *testing.B) {
 	m, err := tilepix.ReadFile("examples/t1.tmx")
 	if err != nil {
@@ -159,3 +210,211 @@ func BenchmarkMap_DrawAll(b *testing.B) {
 		}
 	})
 }
+
+func TestMap_Isometric(t *testing.T) {
+	for _, path := range []string{"testdata/isometric.tmx", "testdata/isometric.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := m.Bounds(), pixel.R(0, 0, 80, 40); got != want {
+				t.Errorf("Bounds() = %v, want %v", got, want)
+			}
+			if got, want := m.GetTileLayerByName("ground").Bounds(), pixel.R(0, 0, 80, 40); got != want {
+				t.Errorf("TileLayer.Bounds() = %v, want %v", got, want)
+			}
+
+			// The top-left tile is at the top of the map, the bottom-right tile at the bottom.
+			tiles := []struct {
+				x, y   int
+				centre pixel.Vec
+			}{
+				{x: 0, y: 0, centre: pixel.V(32, 32)},
+				{x: 2, y: 1, centre: pixel.V(48, 8)},
+				{x: 0, y: 1, centre: pixel.V(16, 24)},
+			}
+			for _, tt := range tiles {
+				if got := m.TileToWorld(tt.x, tt.y); got != tt.centre {
+					t.Errorf("TileToWorld(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.centre)
+				}
+				if x, y := m.WorldToTile(tt.centre); x != tt.x || y != tt.y {
+					t.Errorf("WorldToTile(%v) = %d,%d, want %d,%d", tt.centre, x, y, tt.x, tt.y)
+				}
+			}
+
+			l := m.GetTileLayerByName("ground")
+			if got, want := l.DecodedTiles[5].Position(5, m.Tilesets[0]), pixel.V(40, 8); got != want {
+				t.Errorf("Position() = %v, want %v", got, want)
+			}
+
+			// Rectangles are projected into parallelograms.
+			rect := m.GetObjectByName("rect")[0]
+			vertices, err := rect.GetRectVertices()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []pixel.Vec{pixel.V(32, 24), pixel.V(48, 32), pixel.V(64, 24), pixel.V(48, 16)}
+			for i := range want {
+				if vertices[i] != want[i] {
+					t.Errorf("GetRectVertices() = %v, want %v", vertices, want)
+					break
+				}
+			}
+			if r, _ := rect.GetRect(); r != pixel.R(32, 16, 64, 32) {
+				t.Errorf("GetRect() = %v, want bounding box", r)
+			}
+
+			// Tile objects are aligned by their bottom-centre on isometric maps.
+			tile := m.GetObjectByName("tile")[0]
+			if got, want := pixel.V(tile.X, tile.Y), pixel.V(24, 24); got != want {
+				t.Errorf("Tile object position = %v, want %v", got, want)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Errorf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
+
+func TestMap_Hexagonal(t *testing.T) {
+	for _, path := range []string{"testdata/hexagonal.tmx", "testdata/hexagonal.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.StaggerAxis != "y" || m.StaggerIndex != "odd" || m.HexSideLength != 16 {
+				t.Errorf("Stagger = %s %s %d, want y odd 16", m.StaggerAxis, m.StaggerIndex, m.HexSideLength)
+			}
+			if got, want := m.Bounds(), pixel.R(0, 0, 98, 80); got != want {
+				t.Errorf("Bounds() = %v, want %v", got, want)
+			}
+			if got, want := m.GetTileLayerByName("ground").Bounds(), pixel.R(0, 0, 98, 80); got != want {
+				t.Errorf("TileLayer.Bounds() = %v, want %v", got, want)
+			}
+
+			// The odd rows are shifted right by half a tile.
+			tiles := []struct {
+				x, y   int
+				centre pixel.Vec
+			}{
+				{x: 0, y: 0, centre: pixel.V(14, 64)},
+				{x: 0, y: 1, centre: pixel.V(28, 40)},
+				{x: 2, y: 1, centre: pixel.V(84, 40)},
+				{x: 2, y: 2, centre: pixel.V(70, 16)},
+			}
+			for _, tt := range tiles {
+				if got := m.TileToWorld(tt.x, tt.y); got != tt.centre {
+					t.Errorf("TileToWorld(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.centre)
+				}
+				if x, y := m.WorldToTile(tt.centre); x != tt.x || y != tt.y {
+					t.Errorf("WorldToTile(%v) = %d,%d, want %d,%d", tt.centre, x, y, tt.x, tt.y)
+				}
+			}
+
+			// The 120 degree rotation flag is masked off the GID.
+			l := m.GetTileLayerByName("ground")
+			if tile := l.DecodedTiles[4]; tile.ID != 4 || !tile.RotateHex120 || tile.DiagonalFlip {
+				t.Errorf("DecodedTiles[4] = %v, rotated %t, want ID 4 rotated 120 degrees", tile, tile.RotateHex120)
+			}
+			if tile := l.DecodedTiles[8]; tile.ID != 8 || tile.RotateHex120 || !tile.DiagonalFlip || !tile.HorizontalFlip {
+				t.Errorf("DecodedTiles[8] = %v, want ID 8 flipped", tile)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Errorf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
+
+func TestMap_Staggered(t *testing.T) {
+	for _, path := range []string{"testdata/staggered.tmx", "testdata/staggered.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := m.Bounds(), pixel.R(0, 0, 64, 40); got != want {
+				t.Errorf("Bounds() = %v, want %v", got, want)
+			}
+
+			// The even columns are shifted down by half a tile.
+			tiles := []struct {
+				x, y   int
+				centre pixel.Vec
+			}{
+				{x: 0, y: 0, centre: pixel.V(16, 24)},
+				{x: 1, y: 0, centre: pixel.V(32, 32)},
+				{x: 1, y: 1, centre: pixel.V(32, 16)},
+				{x: 2, y: 1, centre: pixel.V(48, 8)},
+			}
+			for _, tt := range tiles {
+				if got := m.TileToWorld(tt.x, tt.y); got != tt.centre {
+					t.Errorf("TileToWorld(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.centre)
+				}
+				if x, y := m.WorldToTile(tt.centre); x != tt.x || y != tt.y {
+					t.Errorf("WorldToTile(%v) = %d,%d, want %d,%d", tt.centre, x, y, tt.x, tt.y)
+				}
+			}
+		})
+	}
+}
+
+func TestMap_DrawAllParallax(t *testing.T) {
+	for _, path := range []string{"testdata/parallax.tmx", "testdata/parallax.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sky, far, near := m.GetImageLayerByName("sky"), m.GetTileLayerByName("background/far"), m.GetTileLayerByName("near")
+			layers := []struct {
+				layer    tilepix.Layer
+				parallax pixel.Vec
+				offset   pixel.Vec
+			}{
+				// The parallax origin is at (8, 28) in game co-ordinates, the camera 16 to the right and 8 above it.
+				{layer: sky, parallax: pixel.V(0, 0), offset: pixel.V(16, 8)},
+				{layer: far, parallax: pixel.V(0.25, 0.25), offset: pixel.V(12, 6)},
+				{layer: near, parallax: pixel.V(1, 1), offset: pixel.ZV},
+			}
+			for _, tt := range layers {
+				if got := tt.layer.EffectiveParallax(); got != tt.parallax {
+					t.Errorf("%s EffectiveParallax() = %v, want %v", tt.layer, got, tt.parallax)
+				}
+				if got := m.ParallaxOffset(tt.layer, pixel.V(24, 36)); got != tt.offset {
+					t.Errorf("%s ParallaxOffset() = %v, want %v", tt.layer, got, tt.offset)
+				}
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			if err := m.DrawAllParallax(target, color.Transparent, pixel.IM, pixel.V(24, 36)); err != nil {
+				t.Errorf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
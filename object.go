@@ -1,9 +1,12 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
+	"math"
 
 	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,34 +20,89 @@ import (
 
 // Object is a TMX file struture holding a specific Tiled object.
 type Object struct {
-	Name       string      `xml:"name,attr"`
-	Type       string      `xml:"type,attr"`
-	X          float64     `xml:"x,attr"`
-	Y          float64     `xml:"y,attr"`
-	Width      float64     `xml:"width,attr"`
-	Height     float64     `xml:"height,attr"`
-	GID        ID          `xml:"gid,attr"`
-	ID         ID          `xml:"id,attr"`
-	Visible    bool        `xml:"visible,attr"`
-	Polygon    *Polygon    `xml:"polygon"`
-	PolyLine   *PolyLine   `xml:"polyline"`
-	Properties []*Property `xml:"properties>property"`
-	Ellipse    *struct{}   `xml:"ellipse"`
-	Point      *struct{}   `xml:"point"`
-
+	Name   string  `xml:"name,attr"`
+	Type   string  `xml:"type,attr"`
+	X      float64 `xml:"x,attr"`
+	Y      float64 `xml:"y,attr"`
+	Width  float64 `xml:"width,attr"`
+	Height float64 `xml:"height,attr"`
+	// Rotation is the clockwise rotation of the object in degrees, about its position in Tiled.
+	Rotation float64 `xml:"rotation,attr"`
+	// GID is the global ID of the tile of a tile object, including any flip flags.
+	GID        GID        `xml:"gid,attr"`
+	ID         ID         `xml:"id,attr"`
+	Visible    bool       `xml:"visible,attr"`
+	Polygon    *Polygon   `xml:"polygon"`
+	PolyLine   *PolyLine  `xml:"polyline"`
+	Properties Properties `xml:"properties>property"`
+	Ellipse    *struct{}  `xml:"ellipse"`
+	Point      *struct{}  `xml:"point"`
+	Text       *Text      `xml:"text"`
+	// Template is the path of the object template this object is an instance of, relative to the map.  The fields of
+	// the template are merged into the object when the map is read.
+	Template string `xml:"template,attr"`
+
+	// attrs holds the names of the attributes set on a template instance, these override those of the template.
+	attrs      map[string]bool
 	objectType ObjectType
 	tile       *DecodedTile
+	// origin is the position of the object in the pixel co-ordinates of Tiled, and pivot that position in game
+	// co-ordinates; which the object is rotated about.
+	origin pixel.Vec
+	pivot  pixel.Vec
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// DrawText will draw the text of this object to the target, within the rectangle of the object, using the atlas
+// provided.  The atlas is scaled to the pixel size of the text, the font family, style and kerning are not applied.  If
+// atlas is nil `text.Atlas7x13` is used.  If the object type is not `TextObj` this function will return an error.
+func (o *Object) DrawText(target pixel.Target, atlas *text.Atlas) error {
+	t, err := o.GetText()
+	if err != nil {
+		log.WithError(err).Error("Object.DrawText: could not get text")
+		return err
+	}
+
+	if atlas == nil {
+		atlas = text.Atlas7x13
+	}
+	t.draw(target, atlas, pixel.R(o.X, o.Y, o.X+o.Width, o.Y+o.Height), o.rotation(o.pivot))
+
+	return nil
+}
+
+// DrawTile will draw the tile of this object to the target, within the rectangle of the object.  The tile is flipped
+// and rotated as set in Tiled, and scaled when the size of the object differs from that of the tile.  Animated tiles are shown at
+// the time of the maps' clock.  If the object type is not `TileObj` this function will return an error.
+func (o *Object) DrawTile(target pixel.Target) error {
+	tile, err := o.GetTile()
+	if err != nil {
+		log.WithError(err).Error("Object.DrawTile: could not get tile")
+		return err
+	}
+
+	if tile.animation != nil && tile.setFrame(o.parentMap.clock) {
+		tile.setSprite(tile.Tileset.Columns, tile.Tileset.numRows(), tile.Tileset)
+	}
+	if tile.sprite == nil {
+		log.WithError(ErrInvalidPicture).WithField("Object", o).Error("Object.DrawTile: tile has no sprite")
+		return ErrInvalidPicture
+	}
+
+	tile.sprite.Draw(target, o.tileMatrix(tile).Chained(o.rotation(o.pivot)))
+
+	return nil
+}
+
 // GetEllipse will return a pixel.Circle representation of this object relative to the map (the co-ordinates will match
 // those as drawn in Tiled).  If the object type is not `EllipseObj` this function will return `pixel.C(pixel.ZV, 0)`
 // and an error.
 //
 // Because there is no pixel geometry code for irregular ellipses, this function will average the width and height of
-// the ellipse object from the TMX file, and return a regular circle about the centre of the ellipse.
+// the ellipse object from the TMX file, and return a regular circle about the centre of the ellipse.  The centre is
+// rotated with the object about its top-left.
 func (o *Object) GetEllipse() (pixel.Circle, error) {
 	if o.GetType() != EllipseObj {
 		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetEllipse: object type mismatch")
@@ -56,9 +114,9 @@ func (o *Object) GetEllipse() (pixel.Circle, error) {
 	// Because Pixel does not support irregular ellipses, we take the average of width and height.
 	radius := (o.Width + o.Height) / 4
 	// The centre should be the same as the ellipses drawn in Tiled, this will make outputs more intuitive.
-	centre := pixel.V(o.X+(o.Width/2), o.Y+(o.Height/2))
+	centre := o.toGame(pixel.V(o.Width/2, o.Height/2))
 
-	return pixel.C(centre, radius), nil
+	return pixel.C(o.rotation(o.pivot).Project(centre), radius), nil
 }
 
 // GetPoint will return a pixel.Vec representation of this object relative to the map (the co-ordinates will match those
@@ -73,15 +131,28 @@ func (o *Object) GetPoint() (pixel.Vec, error) {
 }
 
 // GetRect will return a pixel.Rect representation of this object relative to the map (the co-ordinates will match those
-// as drawn in Tiled).  If the object type is not `RectangleObj` this function will return `pixel.R(0, 0, 0, 0)` and an
-// error.
+// as drawn in Tiled).  A pixel.Rect cannot be rotated, and on isometric maps rectangles are drawn as parallelograms, so
+// this is the bounding box of the corners returned by GetRectVertices; use those for the exact shape.  If the object
+// type is not `RectangleObj` this function will return `pixel.R(0, 0, 0, 0)` and an error.
 func (o *Object) GetRect() (pixel.Rect, error) {
 	if o.GetType() != RectangleObj {
 		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetRect: object type mismatch")
 		return pixel.R(0, 0, 0, 0), ErrInvalidObjectType
 	}
 
-	return pixel.R(o.X, o.Y, o.X+o.Width, o.Y+o.Height), nil
+	return boundsOf(o.rectVertices()), nil
+}
+
+// GetRectVertices will return the corners of this rectangle object relative to the map, rotated as in Tiled about the
+// top-left of the rectangle.  The corners are in the order returned by pixel.Rect.Vertices.  If the object type is not
+// `RectangleObj` this function will return `nil` and an error.
+func (o *Object) GetRectVertices() ([]pixel.Vec, error) {
+	if o.GetType() != RectangleObj {
+		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetRectVertices: object type mismatch")
+		return nil, ErrInvalidObjectType
+	}
+
+	return o.rectVertices(), nil
 }
 
 // GetPolygon will return a pixel.Vec slice representation of this object relative to the map (the co-ordinates will match
@@ -98,9 +169,10 @@ func (o *Object) GetPolygon() ([]pixel.Vec, error) {
 		return nil, err
 	}
 
+	rotation := o.rotation(o.pointsOrigin())
 	var pixelPoints []pixel.Vec
 	for _, p := range points {
-		pixelPoints = append(pixelPoints, p.V())
+		pixelPoints = append(pixelPoints, rotation.Project(o.pointV(p)))
 	}
 
 	return pixelPoints, nil
@@ -120,16 +192,29 @@ func (o *Object) GetPolyLine() ([]pixel.Vec, error) {
 		return nil, err
 	}
 
+	rotation := o.rotation(o.pointsOrigin())
 	var pixelPoints []pixel.Vec
 	for _, p := range points {
-		pixelPoints = append(pixelPoints, p.V())
+		pixelPoints = append(pixelPoints, rotation.Project(o.pointV(p)))
 	}
 
 	return pixelPoints, nil
 }
 
-// GetTile will return the object decoded into a DecodedTile struct.  If this
-// object is not a DecodedTile, this function will return `nil` and an error.
+// GetText will return the text of this object.  If the object type is not `TextObj` this function will return `nil`
+// and an error.
+func (o *Object) GetText() (*Text, error) {
+	if o.GetType() != TextObj {
+		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetText: object type mismatch")
+		return nil, ErrInvalidObjectType
+	}
+
+	return o.Text, nil
+}
+
+// GetTile will return the object decoded into a DecodedTile struct.  The position of the object is the bottom-left of
+// the tile, taking the object alignment and tile offset of its tileset into account.  If this object is not a
+// DecodedTile, this function will return `nil` and an error.
 func (o *Object) GetTile() (*DecodedTile, error) {
 	if o.GetType() != TileObj {
 		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetTile: object type mismatch")
@@ -137,18 +222,17 @@ func (o *Object) GetTile() (*DecodedTile, error) {
 	}
 
 	if o.tile == nil {
-		// Setting tileset to the first tileset in the map.  Will need updating when dealing with multiple
-		// tilesets.
-		ts := o.parentMap.Tilesets[0]
-
-		o.tile = &DecodedTile{
-			ID:        o.GID,
-			Tileset:   ts,
-			parentMap: o.parentMap,
+		// The GID is decoded in the same way as those of tile layers; giving the tileset and flips of the tile.
+		tile, err := o.parentMap.decodeGID(o.GID)
+		if err != nil {
+			log.WithError(err).WithField("GID", o.GID).Error("Object.GetTile: could not decode GID")
+			return nil, err
 		}
+		tile.setParent(o.parentMap)
 
-		numRows := ts.Tilecount / ts.Columns
-		o.tile.setSprite(ts.Columns, numRows, ts)
+		ts := tile.Tileset
+		tile.setSprite(ts.Columns, ts.numRows(), ts)
+		o.tile = tile
 	}
 
 	return o.tile, nil
@@ -159,12 +243,184 @@ func (o *Object) GetType() ObjectType {
 	return o.objectType
 }
 
+// Props returns the custom properties of the object.
+func (o *Object) Props() Properties {
+	return o.Properties
+}
+
+// Transform returns the matrix which transforms co-ordinates relative to the position of the object in Tiled, with Y
+// up, into game co-ordinates; rotated by the rotation of the object.  The position is the top-left for rectangles,
+// ellipses and text, the first point for polygons and polylines, and for tile objects the point given by the object
+// alignment of the tileset, which is the bottom-left by default.
+func (o *Object) Transform() pixel.Matrix {
+	return pixel.IM.Rotated(pixel.ZV, o.angle()).Moved(o.pivot)
+}
+
 func (o *Object) String() string {
 	return fmt.Sprintf("Object{%s, Name: '%s'}", o.objectType, o.Name)
 }
 
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the object.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (o *Object) UnmarshalProperties(v interface{}) error {
+	return o.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that attributes which Tiled omits when set to their default are
+// initialised correctly, and the attributes set on template instances are recorded.
+func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type object Object
+	v := object{Visible: true}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*o = Object(v)
+	if o.Template != "" {
+		o.attrs = make(map[string]bool, len(start.Attr))
+		for _, attr := range start.Attr {
+			o.attrs[attr.Name.Local] = true
+		}
+	}
+	return nil
+}
+
+// applyTemplate merges the template object into this object.  As in Tiled, attributes set on this object override
+// those of the template, properties are merged by name, and the shape of the template is used unless this object has
+// its own.  The ID and position of the object are never taken from the template.
+func (o *Object) applyTemplate(t *Object) {
+	if !o.attrs["name"] {
+		o.Name = t.Name
+	}
+	if !o.attrs["type"] {
+		o.Type = t.Type
+	}
+	if !o.attrs["width"] {
+		o.Width = t.Width
+	}
+	if !o.attrs["height"] {
+		o.Height = t.Height
+	}
+	if !o.attrs["gid"] {
+		o.GID = t.GID
+	}
+	if !o.attrs["visible"] {
+		o.Visible = t.Visible
+	}
+	if !o.attrs["rotation"] {
+		o.Rotation = t.Rotation
+	}
+
+	// Shapes are copied, as their points are converted in place when the map is initialised.
+	if o.Polygon == nil && o.PolyLine == nil && o.Ellipse == nil && o.Point == nil {
+		if t.Polygon != nil {
+			o.Polygon = &Polygon{Points: t.Polygon.Points}
+		}
+		if t.PolyLine != nil {
+			o.PolyLine = &PolyLine{Points: t.PolyLine.Points}
+		}
+		o.Ellipse, o.Point = t.Ellipse, t.Point
+	}
+	if o.Text == nil && t.Text != nil {
+		tc := *t.Text
+		o.Text = &tc
+	}
+
+	o.Properties = mergeProperties(t.Properties, o.Properties)
+}
+
+// flipY converts the position of the object to game co-ordinates, where it is the bottom-left of the object.  The
+// position of a tile object refers to the point given by the object alignment of its tileset, rather than its
+// top-left, and the tile offset of the tileset is applied as when drawing tiles.  The positions of objects on isometric
+// maps are projected onto the isometric grid.
 func (o *Object) flipY() {
-	o.Y = o.parentMap.pixelHeight() - o.Y - o.Height
+	o.origin = pixel.V(o.X, o.Y)
+	o.pivot = o.parentMap.objectToGame(o.origin)
+
+	if o.GetType() != TileObj {
+		o.X, o.Y = boundsOf(o.corners()).Min.XY()
+		return
+	}
+
+	// Should the tileset not be found, the defaults of an empty tileset are used.
+	ts := &Tileset{}
+	if dt, err := o.parentMap.decodeGID(o.GID); err == nil {
+		ts = dt.Tileset
+	}
+
+	align, offset := ts.alignment(o.parentMap.Orientation), ts.tileOffset()
+	o.X = o.pivot.X + offset.X - align.X*o.Width
+	o.Y = o.pivot.Y - align.Y*o.Height + offset.Y
+}
+
+// angle returns the rotation of the object in radians, anti-clockwise as used by pixel.
+func (o *Object) angle() float64 {
+	return -o.Rotation * math.Pi / 180
+}
+
+// corners returns the corners of the rectangle of the object, before rotation, in game co-ordinates.  The corners are
+// in the order of pixel.Rect.Vertices; bottom-left, top-left, top-right then bottom-right.
+func (o *Object) corners() []pixel.Vec {
+	var corners []pixel.Vec
+	for _, c := range []pixel.Vec{pixel.V(0, o.Height), pixel.ZV, pixel.V(o.Width, 0), pixel.V(o.Width, o.Height)} {
+		corners = append(corners, o.toGame(c))
+	}
+
+	return corners
+}
+
+// pointV returns a point of the polygon or polyline of the object, in the co-ordinates returned by GetPolygon and
+// GetPolyLine.  See pointsOrigin.
+func (o *Object) pointV(p *Point) pixel.Vec {
+	local := pixel.V(p.X, o.parentMap.pixelHeight()-p.Y)
+	offset := o.parentMap.objectToGame(o.origin.Add(local)).Sub(o.parentMap.objectToGame(o.origin))
+	return o.pointsOrigin().Add(offset)
+}
+
+// pointsOrigin returns the position of the object in the co-ordinates of its polygon or polyline points, which are
+// relative to the object but have had their Y co-ordinates flipped against the height of the map.
+func (o *Object) pointsOrigin() pixel.Vec {
+	return pixel.V(0, o.parentMap.pixelHeight())
+}
+
+// rectVertices returns the corners of the rectangle of the object, rotated about its pivot.
+func (o *Object) rectVertices() []pixel.Vec {
+	rotation := o.rotation(o.pivot)
+	var vertices []pixel.Vec
+	for _, v := range o.corners() {
+		vertices = append(vertices, rotation.Project(v))
+	}
+
+	return vertices
+}
+
+// rotation returns the matrix which rotates co-ordinates by the rotation of the object, about the pivot provided.
+func (o *Object) rotation(pivot pixel.Vec) pixel.Matrix {
+	return pixel.IM.Rotated(pivot, o.angle())
+}
+
+// toGame converts a position relative to the position of the object in Tiled to game co-ordinates.  Objects which are
+// not part of a map, such as those generated from the objects of tiles, are already in game co-ordinates.
+func (o *Object) toGame(local pixel.Vec) pixel.Vec {
+	if o.parentMap == nil {
+		return pixel.V(o.X+local.X, o.Y+o.Height-local.Y)
+	}
+	return o.parentMap.objectToGame(o.origin.Add(local))
+}
+
+// tileMatrix returns the matrix which draws the tile sprite provided within the rectangle of the object.
+func (o *Object) tileMatrix(tile *DecodedTile) pixel.Matrix {
+	size := tile.Tileset.tileSize(tile.Frame())
+	scale := pixel.V(1, 1)
+	if o.Width > 0 && size.X > 0 {
+		scale.X = o.Width / size.X
+	}
+	if o.Height > 0 && size.Y > 0 {
+		scale.Y = o.Height / size.Y
+	}
+
+	centre := pixel.V(o.X, o.Y).Add(size.ScaledXY(scale).Scaled(0.5))
+	return tile.flipMatrix().ScaledXY(pixel.ZV, scale).Moved(centre)
 }
 
 // hydrateType will work out what type this object is.
@@ -189,6 +445,11 @@ func (o *Object) hydrateType() {
 		return
 	}
 
+	if o.Text != nil {
+		o.objectType = TextObj
+		return
+	}
+
 	if o.GID != 0 {
 		o.objectType = TileObj
 		return
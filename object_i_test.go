@@ -9,6 +9,7 @@ import (
 
 	"github.com/bcvery1/tilepix"
 	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
 )
 
 func TestObject_GetEllipse(t *testing.T) {
@@ -233,8 +234,9 @@ func TestObject_GetTile(t *testing.T) {
 		{
 			name:   "getting tile",
 			object: o,
+			// GID 1 is the first tile of the tileset, which has the ID 0.
 			want: &tilepix.DecodedTile{
-				ID: 1,
+				ID: 0,
 			},
 			wantErr: false,
 		},
@@ -261,3 +263,157 @@ func TestObject_GetTile(t *testing.T) {
 		})
 	}
 }
+
+func TestObject_GetTileTilesets(t *testing.T) {
+	for _, path := range []string{"testdata/tile_objects.tmx", "testdata/tile_objects.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			white, err := m.GetObjectByName("white")[0].GetTile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if white.ID != 1 || white.Tileset != m.Tilesets[1] || !white.HorizontalFlip || white.VerticalFlip {
+				t.Errorf("Unexpected flipped tile %v from tileset %v", white, white.Tileset)
+			}
+
+			plain, err := m.GetObjectByName("plain")[0].GetTile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain.ID != 2 || plain.Tileset != m.Tilesets[0] || plain.HorizontalFlip {
+				t.Errorf("Unexpected tile %v from tileset %v", plain, plain.Tileset)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			for _, o := range m.GetObjectLayerByName("objects").Objects {
+				if err := o.DrawTile(target); err != nil {
+					t.Errorf("Could not draw %v: %v", o, err)
+				}
+			}
+		})
+	}
+}
+
+func TestObject_Rotation(t *testing.T) {
+	near := func(got, want []pixel.Vec) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i].Sub(want[i]).Len() > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, path := range []string{"testdata/rotation.tmx", "testdata/rotation.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Rotated 90 degrees clockwise about its top-left, at 16,48.
+			rect := m.GetObjectByName("rect")[0]
+			if rect.Rotation != 90 {
+				t.Errorf("Rotation = %f, want 90", rect.Rotation)
+			}
+			r, err := rect.GetRect()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []pixel.Vec{pixel.V(8, 32), pixel.V(16, 48)}; !near([]pixel.Vec{r.Min, r.Max}, want) {
+				t.Errorf("GetRect() = %v, want bounding box of rotated rectangle", r)
+			}
+			vertices, err := rect.GetRectVertices()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []pixel.Vec{pixel.V(8, 48), pixel.V(16, 48), pixel.V(16, 32), pixel.V(8, 32)}; !near(vertices, want) {
+				t.Errorf("GetRectVertices() = %v, want %v", vertices, want)
+			}
+
+			points, err := m.GetObjectByName("triangle")[0].GetPolygon()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []pixel.Vec{pixel.V(0, 64), pixel.V(0, 54), pixel.V(-5, 64)}; !near(points, want) {
+				t.Errorf("GetPolygon() = %v, want %v", points, want)
+			}
+
+			// Tile objects are rotated about their bottom-left.
+			tile := m.GetObjectByName("tile")[0]
+			if got, want := tile.Transform().Project(pixel.V(16, 16)), pixel.V(16, 16); got.Sub(want).Len() > 1e-9 {
+				t.Errorf("Transform().Project() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestObject_GetText(t *testing.T) {
+	for _, path := range []string{"testdata/text.tmx", "testdata/text.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			title := m.GetObjectByName("title")[0]
+			if title.GetType() != tilepix.TextObj {
+				t.Fatalf("Expected text object, got %v", title.GetType())
+			}
+			txt, err := title.GetText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tilepix.Text{
+				Text:       "Hello world, this is a long line",
+				FontFamily: "Serif",
+				PixelSize:  24,
+				Wrap:       true,
+				Color:      "#ff336699",
+				Bold:       true,
+				Italic:     true,
+				Kerning:    true,
+				HAlign:     "center",
+				VAlign:     "bottom",
+			}
+			if *txt != want {
+				t.Errorf("GetText() = %+v, want %+v", *txt, want)
+			}
+			if got, want := txt.GetColor(), pixel.RGB(0x33/255.0, 0x66/255.0, 0x99/255.0); got != want {
+				t.Errorf("GetColor() = %v, want %v", got, want)
+			}
+
+			plain, err := m.GetObjectByName("plain")[0].GetText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain.FontFamily != "sans-serif" || plain.PixelSize != 16 || plain.HAlign != "left" || plain.VAlign != "top" {
+				t.Errorf("Expected default text attributes, got %+v", plain)
+			}
+
+			if _, err := m.GetObjectByName("title")[0].GetRect(); err == nil {
+				t.Error("Expected error getting rectangle of text object")
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := title.DrawText(target, nil); err != nil {
+				t.Errorf("DrawText() error = %v", err)
+			}
+		})
+	}
+}
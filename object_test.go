@@ -1,6 +1,10 @@
 package tilepix
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/gopxl/pixel"
+)
 
 func TestObject_String(t *testing.T) {
 	type fields struct {
@@ -61,12 +65,20 @@ func TestObject_String(t *testing.T) {
 			want: "Object{Tile, Name: 'object 6'}",
 		},
 		{
-			name: "Unknown",
+			name: "Text",
 			fields: fields{
 				Name:       "object 7",
-				objectType: 6,
+				objectType: TextObj,
 			},
-			want: "Object{Unknown, Name: 'object 7'}",
+			want: "Object{Text, Name: 'object 7'}",
+		},
+		{
+			name: "Unknown",
+			fields: fields{
+				Name:       "object 8",
+				objectType: 7,
+			},
+			want: "Object{Unknown, Name: 'object 8'}",
 		},
 	}
 	for _, tt := range tests {
@@ -81,3 +93,42 @@ func TestObject_String(t *testing.T) {
 		})
 	}
 }
+
+func TestObject_tileMatrix(t *testing.T) {
+	m, err := ReadFile("testdata/tile_objects.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := m.GetObjectByName("white")[0]
+	tile, err := o.GetTile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tile is flipped horizontally and stretched to the 32x16 object, whose bottom-left is at 0,32.
+	tests := []struct {
+		local pixel.Vec
+		want  pixel.Vec
+	}{
+		{local: pixel.V(-8, -8), want: pixel.V(32, 32)},
+		{local: pixel.V(8, 8), want: pixel.V(0, 48)},
+		{local: pixel.ZV, want: pixel.V(16, 40)},
+	}
+	for _, tt := range tests {
+		if got := o.tileMatrix(tile).Project(tt.local); got != tt.want {
+			t.Errorf("tileMatrix().Project(%v) = %v, want %v", tt.local, got, tt.want)
+		}
+	}
+}
+
+func TestObject_flipY(t *testing.T) {
+	// The GID is not within any tileset, so the object is aligned as it would be by a tileset with no object alignment.
+	m := &Map{Orientation: "orthogonal", Width: 4, Height: 4, TileWidth: 16, TileHeight: 16}
+	o := &Object{X: 16, Y: 32, Width: 16, Height: 16, GID: 99, objectType: TileObj, parentMap: m}
+
+	o.flipY()
+	if want := pixel.V(16, 32); pixel.V(o.X, o.Y) != want {
+		t.Errorf("flipY() moved the object to %v, want %v", pixel.V(o.X, o.Y), want)
+	}
+}
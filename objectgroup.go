@@ -1,6 +1,12 @@
 package tilepix
 
-import "fmt"
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+
+	"github.com/gopxl/pixel"
+)
 
 /*
    ___  _     _        _    ___
@@ -12,34 +18,53 @@ import "fmt"
 
 // ObjectGroup is a TMX file structure holding a Tiled ObjectGroup.
 type ObjectGroup struct {
-	Name       string      `xml:"name,attr"`
-	Color      string      `xml:"color,attr"`
-	OffSetX    float64     `xml:"offsetx,attr"`
-	OffSetY    float64     `xml:"offsety,attr"`
-	Opacity    float32     `xml:"opacity,attr"`
-	Visible    bool        `xml:"visible,attr"`
-	Properties []*Property `xml:"properties>property"`
-	Objects    []*Object   `xml:"object"`
+	Name       string     `xml:"name,attr"`
+	Color      string     `xml:"color,attr"`
+	OffSetX    float64    `xml:"offsetx,attr"`
+	OffSetY    float64    `xml:"offsety,attr"`
+	Opacity    float32    `xml:"opacity,attr"`
+	Visible    bool       `xml:"visible,attr"`
+	TintColor  string     `xml:"tintcolor,attr"`
+	Properties Properties `xml:"properties>property"`
+	// ParallaxX and ParallaxY are the factors the object group scrolls by relative to the camera, see Map.DrawAllParallax.
+	ParallaxX float64   `xml:"parallaxx,attr"`
+	ParallaxY float64   `xml:"parallaxy,attr"`
+	Objects   []*Object `xml:"object"`
+
+	// tint is the colour mask parsed from TintColor, it is nil when the layer is not tinted.  It is set when the layer is
+	// read and by SetTintColor, so that the tint is only parsed once.
+	tint *pixel.RGBA
 
+	// parentGroup is the group which contains this layer, it is nil for top-level layers.
+	parentGroup *GroupLayer
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
-func (og *ObjectGroup) String() string {
-	return fmt.Sprintf("ObjectGroup{Name: %s, Properties: %v, Objects: %v}", og.Name, og.Properties, og.Objects)
+// EffectiveOffset returns the offset of the object group, including the offsets of all groups containing it.  The offset
+// is in game co-ordinates, so the Y component is inverted from that set in Tiled.
+func (og *ObjectGroup) EffectiveOffset() pixel.Vec {
+	return pixel.V(og.OffSetX, -og.OffSetY).Add(og.parentGroup.effectiveOffset())
 }
 
-func (og *ObjectGroup) decode() error {
-	for _, o := range og.Objects {
-		// Have the object decode its' type
-		o.hydrateType()
+// EffectiveOpacity returns the opacity of the object group, multiplied by the opacity of all groups containing it.
+func (og *ObjectGroup) EffectiveOpacity() float64 {
+	return float64(og.Opacity) * og.parentGroup.effectiveOpacity()
+}
 
-		// Set the x,y offsets of the layer onto the object
-		o.X += og.OffSetX
-		o.Y -= og.OffSetY
-	}
+// EffectiveParallax returns the parallax factors of the object group, multiplied by those of all groups containing it.
+func (og *ObjectGroup) EffectiveParallax() pixel.Vec {
+	return pixel.V(og.ParallaxX, og.ParallaxY).ScaledXY(og.parentGroup.effectiveParallax())
+}
 
-	return nil
+// EffectiveTint returns the tint colour of the object group, multiplied by the tint of all groups containing it.
+func (og *ObjectGroup) EffectiveTint() pixel.RGBA {
+	return tintMask(og.tint).Mul(og.parentGroup.effectiveTint())
+}
+
+// EffectiveVisible returns whether the object group is visible; it is hidden if any group containing it is hidden.
+func (og *ObjectGroup) EffectiveVisible() bool {
+	return og.Visible && og.parentGroup.effectiveVisible()
 }
 
 // GetObjectByName returns the ObjectGroups' Objects by their name
@@ -54,6 +79,67 @@ func (og *ObjectGroup) GetObjectByName(name string) []*Object {
 	return objs
 }
 
+// Props returns the custom properties of the object group.
+func (og *ObjectGroup) Props() Properties {
+	return og.Properties
+}
+
+// SetOpacity sets the opacity of the object group, from 0 for transparent to 1 for opaque.
+func (og *ObjectGroup) SetOpacity(opacity float64) {
+	og.Opacity = float32(opacity)
+}
+
+// SetTintColor sets the tint colour of the object group, which is multiplied with the colour of its objects.  A nil
+// colour removes the tint.
+func (og *ObjectGroup) SetTintColor(c color.Color) {
+	og.TintColor = formatColor(c)
+	og.tint = parseTint(og.TintColor)
+}
+
+// SetVisible sets whether the object group is drawn.
+func (og *ObjectGroup) SetVisible(visible bool) {
+	og.Visible = visible
+}
+
+func (og *ObjectGroup) String() string {
+	return fmt.Sprintf("ObjectGroup{Name: %s, Properties: %v, Objects: %v}", og.Name, og.Properties, og.Objects)
+}
+
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the object group.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (og *ObjectGroup) UnmarshalProperties(v interface{}) error {
+	return og.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, defaulting any attributes Tiled omits.
+func (og *ObjectGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type objectGroup ObjectGroup
+	var v objectGroup
+	setLayerDefaults(&v.Opacity, &v.Visible, &v.ParallaxX, &v.ParallaxY)
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*og = ObjectGroup(v)
+	og.tint = parseTint(og.TintColor)
+	return nil
+}
+
+func (og *ObjectGroup) decode() error {
+	for _, o := range og.Objects {
+		// Have the object decode its' type
+		o.hydrateType()
+
+		// Set the x,y offsets of the layer, and any groups containing it, onto the object.  Objects have not yet been
+		// flipped into game co-ordinates, so the Y offset is applied from the top down as in Tiled.
+		offset := og.EffectiveOffset()
+		o.X += offset.X
+		o.Y -= offset.Y
+	}
+
+	return nil
+}
+
 func (og *ObjectGroup) flipY() {
 	for _, o := range og.Objects {
 		o.flipY()
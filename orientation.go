@@ -0,0 +1,260 @@
+package tilepix
+
+import (
+	"math"
+
+	"github.com/gopxl/pixel"
+)
+
+/*
+   ___        _            _          _    _
+  / _ \  _ _ (_) ___  _ _ | |_  __ _ | |_ (_) ___  _ _
+ | (_) || '_|| |/ -_)| ' \|  _|/ _` ||  _|| |/ _ \| ' \
+  \___/ |_|  |_|\___||_||_|\__|\__,_| \__||_|\___/|_||_|
+*/
+
+// objectToGame converts a position in the pixel co-ordinates Tiled uses for objects to game co-ordinates.  Tiled
+// stores the objects of isometric maps as if the map were orthogonal with square tiles of the tile height, these are
+// projected onto the isometric grid.  The objects of other orientations are stored in pixels from the top-left.
+func (m *Map) objectToGame(v pixel.Vec) pixel.Vec {
+	if m.Orientation == "isometric" {
+		tileHeight := float64(m.TileHeight)
+		v = m.isometricToScreen(v.X/tileHeight, v.Y/tileHeight)
+	}
+
+	return pixel.V(v.X, m.pixelHeight()-v.Y)
+}
+
+// pixelSize returns the size of the map in pixels, for maps which are not infinite.
+func (m *Map) pixelSize() pixel.Vec {
+	switch m.Orientation {
+	case "isometric":
+		return pixel.V(float64((m.Width+m.Height)*m.TileWidth/2), float64((m.Width+m.Height)*m.TileHeight/2))
+	case "staggered", "hexagonal":
+		p := m.staggerParams()
+		if p.staggerX {
+			size := pixel.V(float64(m.Width)*p.columnWidth+p.sideOffsetX, float64(m.Height)*(p.tileHeight+p.sideLengthY))
+			if m.Width > 1 {
+				size.Y += p.rowHeight
+			}
+			return size
+		}
+
+		size := pixel.V(float64(m.Width)*(p.tileWidth+p.sideLengthX), float64(m.Height)*p.rowHeight+p.sideOffsetY)
+		if m.Height > 1 {
+			size.X += p.columnWidth
+		}
+		return size
+	}
+
+	return pixel.V(float64(m.Width*m.TileWidth), float64(m.Height*m.TileHeight))
+}
+
+// tileCell returns the area, in game co-ordinates, of the cell at the tile co-ordinates provided.  Tiles are drawn
+// aligned to the bottom-left of their cell.  The cells of isometric, staggered and hexagonal maps are the bounding box
+// of the diamond or hexagon shaped tile.
+func (m *Map) tileCell(x, y int) pixel.Rect {
+	cellSize := pixel.V(float64(m.TileWidth), float64(m.TileHeight))
+
+	var min pixel.Vec
+	switch m.Orientation {
+	case "isometric":
+		// The top corner of the diamond is projected, the cell extends half a tile to either side of it.
+		top := m.isometricToScreen(float64(x), float64(y))
+		min = pixel.V(top.X-cellSize.X/2, m.pixelHeight()-top.Y-cellSize.Y)
+	case "staggered", "hexagonal":
+		topLeft := m.staggeredToScreen(x, y)
+		min = pixel.V(topLeft.X, m.pixelHeight()-topLeft.Y-cellSize.Y)
+	default:
+		min = tileToGamePos(x, y, m.Height).ScaledXY(cellSize)
+	}
+
+	return pixel.Rect{Min: min, Max: min.Add(cellSize)}
+}
+
+// worldToTile returns the tile co-ordinates of the cell covering the game position provided.
+func (m *Map) worldToTile(pos pixel.Vec) (x, y int) {
+	tileWidth, tileHeight := float64(m.TileWidth), float64(m.TileHeight)
+
+	switch m.Orientation {
+	case "isometric":
+		// The inverse of isometricToScreen, from the top-left of the map.
+		px := pos.X - float64(m.Height)*tileWidth/2
+		py := m.pixelHeight() - pos.Y
+		tileX, tileY := px/tileWidth, py/tileHeight
+		return int(math.Floor(tileY + tileX)), int(math.Floor(tileY - tileX))
+	case "staggered", "hexagonal":
+		return m.screenToStaggered(pos.X, m.pixelHeight()-pos.Y)
+	}
+
+	x = int(math.Floor(pos.X / tileWidth))
+	y = m.Height - 1 - int(math.Floor(pos.Y/tileHeight))
+	return x, y
+}
+
+// isometricToScreen projects a position in tiles onto an isometric map, returning the position in pixels from the
+// top-left of the map.  The top-left tile is at the top centre of the map, with X increasing down to the right and Y
+// down to the left.
+func (m *Map) isometricToScreen(tileX, tileY float64) pixel.Vec {
+	tileWidth, tileHeight := float64(m.TileWidth), float64(m.TileHeight)
+	originX := float64(m.Height) * tileWidth / 2
+
+	return pixel.V((tileX-tileY)*tileWidth/2+originX, (tileX+tileY)*tileHeight/2)
+}
+
+// staggerParams holds the measurements of the cells of staggered and hexagonal maps, calculated as Tiled does.
+// Staggered maps are hexagonal maps where the hexagons have no sides, making them diamonds.
+type staggerParams struct {
+	tileWidth, tileHeight    float64
+	sideLengthX, sideLengthY float64
+	sideOffsetX, sideOffsetY float64
+	columnWidth, rowHeight   float64
+	// staggerX is whether alternate columns, rather than rows, are shifted.  staggerEven is whether the even, rather
+	// than odd, columns or rows are shifted.
+	staggerX, staggerEven bool
+	// hexagonal is whether the map is hexagonal, rather than staggered.
+	hexagonal bool
+}
+
+func (m *Map) staggerParams() staggerParams {
+	p := staggerParams{
+		// Tiled rounds the tile size down to an even number of pixels.
+		tileWidth:   float64(m.TileWidth &^ 1),
+		tileHeight:  float64(m.TileHeight &^ 1),
+		staggerX:    m.StaggerAxis == "x",
+		staggerEven: m.StaggerIndex == "even",
+		hexagonal:   m.Orientation == "hexagonal",
+	}
+
+	if p.hexagonal {
+		if p.staggerX {
+			p.sideLengthX = float64(m.HexSideLength)
+		} else {
+			p.sideLengthY = float64(m.HexSideLength)
+		}
+	}
+
+	p.sideOffsetX = math.Floor((p.tileWidth - p.sideLengthX) / 2)
+	p.sideOffsetY = math.Floor((p.tileHeight - p.sideLengthY) / 2)
+	p.columnWidth = p.sideOffsetX + p.sideLengthX
+	p.rowHeight = p.sideOffsetY + p.sideLengthY
+
+	return p
+}
+
+// doStagger returns whether the column or row with the index provided is shifted.
+func (p staggerParams) doStagger(index int) bool {
+	return (index&1 != 0) != p.staggerEven
+}
+
+// staggeredToScreen returns the top-left of the cell at the tile co-ordinates provided on a staggered or hexagonal
+// map, in pixels from the top-left of the map.
+func (m *Map) staggeredToScreen(x, y int) pixel.Vec {
+	p := m.staggerParams()
+
+	if p.staggerX {
+		pos := pixel.V(float64(x)*p.columnWidth, float64(y)*(p.tileHeight+p.sideLengthY))
+		if p.doStagger(x) {
+			pos.Y += p.rowHeight
+		}
+		return pos
+	}
+
+	pos := pixel.V(float64(x)*(p.tileWidth+p.sideLengthX), float64(y)*p.rowHeight)
+	if p.doStagger(y) {
+		pos.X += p.columnWidth
+	}
+	return pos
+}
+
+// screenToStaggered returns the tile co-ordinates of the cell at the position provided on a staggered or hexagonal
+// map, in pixels from the top-left of the map.  The position is placed within a block of four cells which repeats
+// across the map, and the nearest of their centres is found.
+func (m *Map) screenToStaggered(px, py float64) (x, y int) {
+	p := m.staggerParams()
+
+	if p.staggerX {
+		if p.staggerEven {
+			px -= p.tileWidth
+		} else {
+			px -= p.sideOffsetX
+		}
+	} else {
+		if p.staggerEven {
+			py -= p.tileHeight
+		} else {
+			py -= p.sideOffsetY
+		}
+	}
+
+	// The block, and the position relative to its top-left.
+	blockX, blockY := math.Floor(px/(p.columnWidth*2)), math.Floor(py/(p.rowHeight*2))
+	rel := pixel.V(px-blockX*p.columnWidth*2, py-blockY*p.rowHeight*2)
+
+	x, y = int(blockX), int(blockY)
+	if p.staggerX {
+		x *= 2
+		if p.staggerEven {
+			x++
+		}
+	} else {
+		y *= 2
+		if p.staggerEven {
+			y++
+		}
+	}
+
+	var centres [4]pixel.Vec
+	var offsets [4][2]int
+	if p.staggerX {
+		left := math.Floor(p.sideLengthX / 2)
+		centreX, centreY := left+p.columnWidth, math.Floor(p.tileHeight/2)
+		centres = [4]pixel.Vec{
+			pixel.V(left, centreY),
+			pixel.V(centreX, centreY-p.rowHeight),
+			pixel.V(centreX, centreY+p.rowHeight),
+			pixel.V(centreX+p.columnWidth, centreY),
+		}
+		offsets = [4][2]int{{0, 0}, {1, -1}, {1, 0}, {2, 0}}
+	} else {
+		top := math.Floor(p.sideLengthY / 2)
+		centreX, centreY := math.Floor(p.tileWidth/2), top+p.rowHeight
+		centres = [4]pixel.Vec{
+			pixel.V(centreX, top),
+			pixel.V(centreX-p.columnWidth, centreY),
+			pixel.V(centreX+p.columnWidth, centreY),
+			pixel.V(centreX, centreY+p.rowHeight),
+		}
+		offsets = [4][2]int{{0, 0}, {-1, 1}, {0, 1}, {0, 2}}
+	}
+
+	nearest, minDist := 0, math.Inf(1)
+	for i, c := range centres {
+		d := rel.Sub(c)
+		// Diamonds are the cells within a distance of half a tile, when measured across both axes.
+		dist := math.Abs(d.X)/p.tileWidth + math.Abs(d.Y)/p.tileHeight
+		if p.hexagonal {
+			dist = d.Len()
+		}
+		if dist < minDist {
+			nearest, minDist = i, dist
+		}
+	}
+
+	return x + offsets[nearest][0], y + offsets[nearest][1]
+}
+
+// boundsOf returns the smallest rectangle which contains all of the points provided.
+func boundsOf(points []pixel.Vec) pixel.Rect {
+	if len(points) == 0 {
+		return pixel.R(0, 0, 0, 0)
+	}
+
+	bounds := pixel.Rect{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		bounds.Min = pixel.V(math.Min(bounds.Min.X, p.X), math.Min(bounds.Min.Y, p.Y))
+		bounds.Max = pixel.V(math.Max(bounds.Max.X, p.X), math.Max(bounds.Max.Y, p.Y))
+	}
+
+	return bounds
+}
@@ -18,20 +18,20 @@ import (
 
 // Point is a TMX file structure holding a Tiled Point object.
 type Point struct {
-	X int
-	Y int
+	X float64
+	Y float64
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
 func (p *Point) String() string {
-	return fmt.Sprintf("Point{%d, %d}", p.X, p.Y)
+	return fmt.Sprintf("Point{%v, %v}", p.X, p.Y)
 }
 
 // V converts the Tiled Point to a Pixel Vector.
 func (p *Point) V() pixel.Vec {
-	return pixel.V(float64(p.X), float64(p.Y))
+	return pixel.V(p.X, p.Y)
 }
 
 func (p *Point) setParent(m *Map) {
@@ -52,13 +52,13 @@ func decodePoints(s string) ([]*Point, error) {
 
 		point := &Point{}
 
-		point.X, err = strconv.Atoi(coordStrings[0])
+		point.X, err = strconv.ParseFloat(coordStrings[0], 64)
 		if err != nil {
 			log.WithError(err).WithField("Point string", coordStrings[0]).Error("decodePoints: could not parse X co-ordinate string")
 			return nil, err
 		}
 
-		point.Y, err = strconv.Atoi(coordStrings[1])
+		point.Y, err = strconv.ParseFloat(coordStrings[1], 64)
 		if err != nil {
 			log.WithError(err).WithField("Point string", coordStrings[1]).Error("decodePoints: could not parse X co-ordinate string")
 			return nil, err
@@ -73,5 +73,5 @@ func decodePoints(s string) ([]*Point, error) {
 // flipY will get the inverse Y co-ordinate based on the parent maps' size.  This is because Tiled draws from the
 // top-right instead of the bottom-left.
 func (p *Point) flipY() {
-	p.Y = int(p.parentMap.pixelHeight()) - p.Y
+	p.Y = p.parentMap.pixelHeight() - p.Y
 }
@@ -4,8 +4,8 @@ import "testing"
 
 func TestPoint_String(t *testing.T) {
 	type fields struct {
-		X int
-		Y int
+		X float64
+		Y float64
 	}
 	tests := []struct {
 		name   string
@@ -20,6 +20,14 @@ func TestPoint_String(t *testing.T) {
 			},
 			want: "Point{1, 2}",
 		},
+		{
+			name: "Fractional string",
+			fields: fields{
+				X: 1.5,
+				Y: -2.25,
+			},
+			want: "Point{1.5, -2.25}",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
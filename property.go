@@ -1,6 +1,14 @@
 package tilepix
 
-import "fmt"
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
 
 /*
   ___                       _
@@ -10,19 +18,270 @@ import "fmt"
              |_|                |__/
 */
 
-// Property is a TMX file structure which holds a Tiled property.
+// PropertyType is the type of a Tiled custom property.
+type PropertyType string
+
+// These are the property types supported by Tiled.
+const (
+	StringProp PropertyType = "string"
+	IntProp    PropertyType = "int"
+	FloatProp  PropertyType = "float"
+	BoolProp   PropertyType = "bool"
+	ColorProp  PropertyType = "color"
+	FileProp   PropertyType = "file"
+	ObjectProp PropertyType = "object"
+	ClassProp  PropertyType = "class"
+)
+
+// Property is a TMX file structure which holds a Tiled property.  Properties of the class type hold their members in
+// Properties; Tiled only saves members which differ from the defaults of the class.
 type Property struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
+	Name  string       `xml:"name,attr"`
+	Type  PropertyType `xml:"type,attr"`
+	Value string       `xml:"value,attr"`
+	// PropertyType is the name of the custom type of class and enum properties.
+	PropertyType string     `xml:"propertytype,attr"`
+	Properties   Properties `xml:"properties>property"`
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// Bool returns the value of the property as a bool.
+func (p *Property) Bool() (bool, error) {
+	return strconv.ParseBool(p.Value)
+}
+
+// Color returns the value of the property as a colour.  Tiled stores colours as "#AARRGGBB".
+func (p *Property) Color() (color.NRGBA, error) {
+	return parseColor(p.Value)
+}
+
+// Float returns the value of the property as a float64.
+func (p *Property) Float() (float64, error) {
+	return strconv.ParseFloat(p.Value, 64)
+}
+
+// Int returns the value of the property as an int.
+func (p *Property) Int() (int, error) {
+	return strconv.Atoi(p.Value)
+}
+
+// Object returns the object the property refers to, or nil if the property does not refer to an object in the map.
+func (p *Property) Object() *Object {
+	id, err := strconv.ParseUint(p.Value, 10, 32)
+	if err != nil || id == 0 || p.parentMap == nil {
+		return nil
+	}
+
+	return p.parentMap.GetObjectByID(ID(id))
+}
+
+// Props returns the members of a class property.
+func (p *Property) Props() Properties {
+	return p.Properties
+}
+
 func (p *Property) String() string {
+	if p.Type == ClassProp {
+		return fmt.Sprintf("Property{%s: %v}", p.Name, p.Properties)
+	}
 	return fmt.Sprintf("Property{%s: %s}", p.Name, p.Value)
 }
 
+// UnmarshalProperties fills the struct pointed to by v from the members of a class property.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (p *Property) UnmarshalProperties(v interface{}) error {
+	return p.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that properties without a type, which Tiled writes for strings, are
+// given the string type.  Tiled writes multi-line strings as the content of the element rather than the value
+// attribute.
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type property Property
+	prop := struct {
+		property
+		Text string `xml:",chardata"`
+	}{property: property{Type: StringProp}}
+	if err := d.DecodeElement(&prop, &start); err != nil {
+		return err
+	}
+
+	*p = Property(prop.property)
+	if p.Value == "" && strings.TrimSpace(prop.Text) != "" {
+		p.Value = prop.Text
+	}
+	return nil
+}
+
 func (p *Property) setParent(m *Map) {
 	p.parentMap = m
+
+	for _, member := range p.Properties {
+		member.setParent(m)
+	}
+}
+
+// mergeProperties returns the base properties overridden by those of the same name in overrides, followed by the
+// properties only in overrides.  The members of class properties are merged in the same way.
+func mergeProperties(base, overrides Properties) Properties {
+	if len(base) == 0 {
+		return overrides
+	}
+
+	merged := make(Properties, 0, len(base)+len(overrides))
+	for _, p := range base {
+		override := overrides.get(p.Name)
+		if override == nil {
+			pc := *p
+			merged = append(merged, &pc)
+			continue
+		}
+
+		if p.Type == ClassProp && override.Type == ClassProp {
+			pc := *override
+			pc.Properties = mergeProperties(p.Properties, override.Properties)
+			merged = append(merged, &pc)
+			continue
+		}
+		merged = append(merged, override)
+	}
+
+	for _, p := range overrides {
+		if base.get(p.Name) == nil {
+			merged = append(merged, p)
+		}
+	}
+
+	return merged
+}
+
+// Properties is a list of Tiled custom properties, with helpers to look them up by name.  Members of class properties
+// can be looked up by a path of names separated by dots, such as "stats.hp".  The typed helpers return the default
+// provided where the property is missing or its value could not be parsed.
+type Properties []*Property
+
+// Bool returns the named property as a bool.
+func (ps Properties) Bool(name string, def bool) bool {
+	p := ps.Get(name)
+	if p == nil {
+		return def
+	}
+
+	v, err := p.Bool()
+	if err != nil {
+		log.WithError(err).WithField("Property", p).Warn("Properties.Bool: could not parse property")
+		return def
+	}
+	return v
+}
+
+// Color returns the named property as a colour.  Colour properties which have not been set are treated as missing.
+func (ps Properties) Color(name string, def color.NRGBA) color.NRGBA {
+	p := ps.Get(name)
+	if p == nil || p.Value == "" {
+		return def
+	}
+
+	v, err := p.Color()
+	if err != nil {
+		log.WithError(err).WithField("Property", p).Warn("Properties.Color: could not parse property")
+		return def
+	}
+	return v
+}
+
+// File returns the named property as a file path.  The path is as stored in Tiled; relative to the file which defines
+// the property.
+func (ps Properties) File(name string, def string) string {
+	p := ps.Get(name)
+	if p == nil || p.Value == "" {
+		return def
+	}
+	return p.Value
+}
+
+// Float returns the named property as a float64.
+func (ps Properties) Float(name string, def float64) float64 {
+	p := ps.Get(name)
+	if p == nil {
+		return def
+	}
+
+	v, err := p.Float()
+	if err != nil {
+		log.WithError(err).WithField("Property", p).Warn("Properties.Float: could not parse property")
+		return def
+	}
+	return v
+}
+
+// Get returns the named property, or nil if there is no property with that name.
+func (ps Properties) Get(name string) *Property {
+	if p := ps.get(name); p != nil {
+		return p
+	}
+
+	// Property names may themselves contain dots, so try each dot as the separator between a class and its member.
+	for i := strings.IndexByte(name, '.'); i >= 0; {
+		if class := ps.Get(name[:i]); class != nil {
+			if p := class.Properties.Get(name[i+1:]); p != nil {
+				return p
+			}
+		}
+
+		next := strings.IndexByte(name[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	return nil
+}
+
+// Int returns the named property as an int.
+func (ps Properties) Int(name string, def int) int {
+	p := ps.Get(name)
+	if p == nil {
+		return def
+	}
+
+	v, err := p.Int()
+	if err != nil {
+		log.WithError(err).WithField("Property", p).Warn("Properties.Int: could not parse property")
+		return def
+	}
+	return v
+}
+
+// Object returns the object referred to by the named property, or nil if the property is missing or does not refer to
+// an object in the map.
+func (ps Properties) Object(name string) *Object {
+	p := ps.Get(name)
+	if p == nil {
+		return nil
+	}
+	return p.Object()
+}
+
+// String returns the named property as a string.
+func (ps Properties) String(name string, def string) string {
+	p := ps.Get(name)
+	if p == nil {
+		return def
+	}
+	return p.Value
+}
+
+// get returns the property with exactly the name provided, without looking into class properties.
+func (ps Properties) get(name string) *Property {
+	for _, p := range ps {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,148 @@
+package tilepix_test
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+)
+
+func TestProperties(t *testing.T) {
+	for _, path := range []string{"testdata/properties.tmx", "testdata/properties.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			props := m.Props()
+			if got := props.String("title", ""); got != "Level one" {
+				t.Errorf("Properties.String() = %v, want Level one", got)
+			}
+			if got := props.String("intro", ""); got != "Welcome\nto level one" {
+				t.Errorf("Properties.String() = %q, want multi-line string", got)
+			}
+			if got := props.Int("speed", 0); got != 12 {
+				t.Errorf("Properties.Int() = %v, want 12", got)
+			}
+			if got := props.Float("gravity", 0); got != 9.5 {
+				t.Errorf("Properties.Float() = %v, want 9.5", got)
+			}
+			if got := props.Bool("night", false); !got {
+				t.Errorf("Properties.Bool() = %v, want true", got)
+			}
+			if got, want := props.Color("sky", color.NRGBA{}), (color.NRGBA{R: 0x33, G: 0x66, B: 0x99, A: 0xff}); got != want {
+				t.Errorf("Properties.Color() = %v, want %v", got, want)
+			}
+			if got := props.File("music", ""); got != "music/level1.ogg" {
+				t.Errorf("Properties.File() = %v, want music/level1.ogg", got)
+			}
+			if o := props.Object("spawn"); o == nil || o.Name != "spawn" {
+				t.Errorf("Properties.Object() = %v, want spawn object", o)
+			}
+			if p := props.Get("speed"); p == nil || p.Type != tilepix.IntProp {
+				t.Errorf("Properties.Get() = %v, want int property", p)
+			}
+			if p := props.Get("title"); p == nil || p.Type != tilepix.StringProp {
+				t.Errorf("Properties.Get() = %v, want string property", p)
+			}
+
+			if got := props.Int("boss.stats.hp", 0); got != 40 {
+				t.Errorf("Properties.Int() = %v, want 40 for class member", got)
+			}
+			if got := props.Float("boss.stats.armor", 0); got != 2.5 {
+				t.Errorf("Properties.Float() = %v, want 2.5 for class member", got)
+			}
+			if got := props.String("boss.name", ""); got != "Ogre" {
+				t.Errorf("Properties.String() = %v, want Ogre for class member", got)
+			}
+			if boss := props.Get("boss"); boss == nil || boss.Type != tilepix.ClassProp || boss.PropertyType != "Enemy" {
+				t.Errorf("Properties.Get() = %v, want Enemy class property", boss)
+			} else if stats := boss.Props().Get("stats"); stats == nil || stats.Type != tilepix.ClassProp || len(stats.Properties) != 2 {
+				t.Errorf("Property.Props().Get() = %v, want stats class property", stats)
+			}
+			if p := props.Get("boss.stats.missing"); p != nil {
+				t.Errorf("Properties.Get() = %v, want nil for missing class member", p)
+			}
+
+			if got := props.Int("missing", 7); got != 7 {
+				t.Errorf("Properties.Int() = %v, want default 7", got)
+			}
+			if got := props.Int("title", 7); got != 7 {
+				t.Errorf("Properties.Int() = %v, want default 7 for non-integer", got)
+			}
+
+			if !m.GetTileLayerByName("ground").Props().Bool("solid", false) {
+				t.Error("Expected ground layer to be solid")
+			}
+			og := m.GetObjectLayerByName("objects")
+			if got := og.Props().Int("layer", 0); got != 3 {
+				t.Errorf("ObjectGroup.Props().Int() = %v, want 3", got)
+			}
+
+			door := m.GetObjectByName("door")[0]
+			if door.Props().Bool("locked", true) {
+				t.Error("Expected door to be unlocked")
+			}
+			if target := door.Props().Object("target"); target != m.GetObjectByID(2) {
+				t.Errorf("Object.Props().Object() = %v, want %v", target, m.GetObjectByID(2))
+			}
+		})
+	}
+}
+
+func TestObject_UnmarshalProperties(t *testing.T) {
+	type level struct {
+		Title string          `tiled:"title"`
+		Speed int             `tiled:"speed"`
+		Lives int             `tiled:"lives,default=3"`
+		Spawn *tilepix.Object `tiled:"spawn"`
+		Boss  struct {
+			Name  string `tiled:"name"`
+			Stats struct {
+				HP    int     `tiled:"hp"`
+				Armor float64 `tiled:"armor"`
+			} `tiled:"stats"`
+		} `tiled:"boss"`
+	}
+	type door struct {
+		Target *tilepix.Object `tiled:"target"`
+		Locked bool            `tiled:"locked"`
+	}
+
+	for _, path := range []string{"testdata/properties.tmx", "testdata/properties.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var l level
+			if err := m.UnmarshalProperties(&l); err != nil {
+				t.Fatal(err)
+			}
+			if l.Title != "Level one" || l.Speed != 12 || l.Lives != 3 || l.Spawn != m.GetObjectByID(2) {
+				t.Errorf("Map.UnmarshalProperties() = %+v", l)
+			}
+			if l.Boss.Name != "Ogre" || l.Boss.Stats.HP != 40 || l.Boss.Stats.Armor != 2.5 {
+				t.Errorf("Map.UnmarshalProperties() boss = %+v", l.Boss)
+			}
+
+			var d door
+			if err := m.GetObjectByName("door")[0].UnmarshalProperties(&d); err != nil {
+				t.Fatal(err)
+			}
+			if d.Target == nil || d.Target.Name != "spawn" || d.Locked {
+				t.Errorf("Object.UnmarshalProperties() = %+v", d)
+			}
+
+			var missing struct {
+				Key string `tiled:"key"`
+			}
+			if err := m.GetObjectByName("door")[0].UnmarshalProperties(&missing); !errors.Is(err, tilepix.ErrMissingProperty) {
+				t.Errorf("Object.UnmarshalProperties() error = %v, want %v", err, tilepix.ErrMissingProperty)
+			}
+		})
+	}
+}
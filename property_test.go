@@ -1,11 +1,16 @@
 package tilepix
 
-import "testing"
+import (
+	"image/color"
+	"testing"
+)
 
 func TestProperty_String(t *testing.T) {
 	type fields struct {
-		Name  string
-		Value string
+		Name       string
+		Type       PropertyType
+		Value      string
+		Properties Properties
 	}
 	tests := []struct {
 		name   string
@@ -20,12 +25,25 @@ func TestProperty_String(t *testing.T) {
 			},
 			want: "Property{name p: value p}",
 		},
+		{
+			name: "Class",
+			fields: fields{
+				Name: "stats",
+				Type: ClassProp,
+				Properties: Properties{
+					{Name: "hp", Type: IntProp, Value: "10"},
+				},
+			},
+			want: "Property{stats: [Property{hp: 10}]}",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Property{
-				Name:  tt.fields.Name,
-				Value: tt.fields.Value,
+				Name:       tt.fields.Name,
+				Type:       tt.fields.Type,
+				Value:      tt.fields.Value,
+				Properties: tt.fields.Properties,
 			}
 			if got := p.String(); got != tt.want {
 				t.Errorf("String() = %v, want %v", got, tt.want)
@@ -33,3 +51,50 @@ func TestProperty_String(t *testing.T) {
 		})
 	}
 }
+
+func TestProperties_Defaults(t *testing.T) {
+	props := Properties{
+		{Name: "speed", Type: IntProp, Value: "fast"},
+		{Name: "ratio", Type: FloatProp, Value: "0.25"},
+		{Name: "tint", Type: ColorProp, Value: ""},
+		{Name: "enabled", Type: BoolProp, Value: "1"},
+	}
+
+	if got := props.Int("speed", 3); got != 3 {
+		t.Errorf("Int() = %v, want 3", got)
+	}
+	if got := props.Float("ratio", 0); got != 0.25 {
+		t.Errorf("Float() = %v, want 0.25", got)
+	}
+	if got, want := props.Color("tint", color.NRGBA{A: 0xff}), (color.NRGBA{A: 0xff}); got != want {
+		t.Errorf("Color() = %v, want %v", got, want)
+	}
+	if got := props.Bool("enabled", false); !got {
+		t.Errorf("Bool() = %v, want true", got)
+	}
+	if got := props.Object("speed"); got != nil {
+		t.Errorf("Object() = %v, want nil", got)
+	}
+	if got := props.Get("missing"); got != nil {
+		t.Errorf("Get() = %v, want nil", got)
+	}
+}
+
+func TestProperties_GetDotted(t *testing.T) {
+	props := Properties{
+		{Name: "a.b", Value: "dotted"},
+		{Name: "a", Type: ClassProp, Properties: Properties{
+			{Name: "c", Value: "member"},
+		}},
+	}
+
+	if p := props.Get("a.b"); p == nil || p.Value != "dotted" {
+		t.Errorf("Get() = %v, want dotted name", p)
+	}
+	if p := props.Get("a.c"); p == nil || p.Value != "member" {
+		t.Errorf("Get() = %v, want class member", p)
+	}
+	if p := props.Get("a.d"); p != nil {
+		t.Errorf("Get() = %v, want nil", p)
+	}
+}
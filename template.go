@@ -0,0 +1,188 @@
+package tilepix
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _____                   _        _
+ |_   _|___  _ __   _ __ | | __ _ | |_  ___
+   | | / -_)| '  \ | '_ \| |/ _` ||  _|/ -_)
+   |_| \___||_|_|_|| .__/|_|\__,_| \__|\___|
+                   |_|
+*/
+
+// objectTemplate is a TX file structure holding a Tiled object template.  Templates of tile objects hold the tileset
+// the tile belongs to, the GID of the object is relative to this tileset rather than those of the map.
+type objectTemplate struct {
+	Tileset *Tileset `xml:"tileset"`
+	Object  *Object  `xml:"object"`
+}
+
+type jsonTemplate struct {
+	Tileset *jsonTileset `json:"tileset"`
+	Object  *jsonObject  `json:"object"`
+}
+
+// readTemplate will read an object template in either the TX or JSON format.  The format is chosen from the extension
+// of name, and when that is not recognised by sniffing the content.
+func readTemplate(r io.Reader, name string) (*objectTemplate, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".tx" || ext == ".xml":
+		return readTemplateXML(r)
+	case isJSONFile(name):
+		return readTemplateJSON(r)
+	}
+
+	br := bufio.NewReader(r)
+	if isJSONContent(br) {
+		log.WithField("Name", name).Debug("readTemplate: content sniffed as JSON")
+		return readTemplateJSON(br)
+	}
+
+	return readTemplateXML(br)
+}
+
+func readTemplateJSON(r io.Reader) (*objectTemplate, error) {
+	var jt jsonTemplate
+	if err := json.NewDecoder(r).Decode(&jt); err != nil {
+		log.WithError(err).Error("readTemplateJSON: could not decode to template")
+		return nil, err
+	}
+	if jt.Object == nil {
+		log.WithError(ErrInvalidTemplate).Error("readTemplateJSON: template has no object")
+		return nil, ErrInvalidTemplate
+	}
+
+	t := &objectTemplate{Object: jt.Object.toObject()}
+	if jt.Tileset != nil {
+		t.Tileset = jt.Tileset.toTileset()
+	}
+
+	return t, nil
+}
+
+func readTemplateXML(r io.Reader) (*objectTemplate, error) {
+	var t objectTemplate
+	if err := xml.NewDecoder(r).Decode(&t); err != nil {
+		log.WithError(err).Error("readTemplateXML: could not decode to template")
+		return nil, err
+	}
+	if t.Object == nil {
+		log.WithError(ErrInvalidTemplate).Error("readTemplateXML: template has no object")
+		return nil, ErrInvalidTemplate
+	}
+
+	return &t, nil
+}
+
+// loadTemplate returns the template object at the path provided, relative to the map.  Each template file is read
+// once, using the maps' file opener, and then cached.  The GID of tile templates is converted to a GID of the map,
+// adding the templates' tileset to the map when it is not already in use.
+func (m *Map) loadTemplate(source string) (*Object, error) {
+	path := filepath.Join(m.dir, source)
+	if o, ok := m.templates[path]; ok {
+		return o, nil
+	}
+
+	f, err := m.openFileFunc(path)
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("Map.loadTemplate: could not open template")
+		return nil, err
+	}
+	t, err := readTemplate(f, path)
+	_ = f.Close()
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("Map.loadTemplate: could not read template")
+		return nil, err
+	}
+
+	if t.Object.GID != 0 && t.Tileset != nil {
+		ts, err := m.templateTileset(t.Tileset, filepath.Dir(path))
+		if err != nil {
+			log.WithError(err).WithField("Filepath", path).Error("Map.loadTemplate: could not load template tileset")
+			return nil, err
+		}
+
+		flags := t.Object.GID & gidFlip
+		t.Object.GID = t.Object.GID&^gidFlip - t.Tileset.FirstGID + ts.FirstGID | flags
+	}
+
+	if m.templates == nil {
+		m.templates = make(map[string]*Object)
+	}
+	m.templates[path] = t.Object
+
+	return t.Object, nil
+}
+
+// resolveTemplates merges every object which is an instance of a template with its template.
+func (m *Map) resolveTemplates() error {
+	for _, og := range m.allObjectGroups() {
+		for _, o := range og.Objects {
+			if o.Template == "" {
+				continue
+			}
+
+			t, err := m.loadTemplate(o.Template)
+			if err != nil {
+				log.WithError(err).WithField("Object", o).Error("Map.resolveTemplates: could not load template")
+				return err
+			}
+			o.applyTemplate(t)
+		}
+	}
+
+	return nil
+}
+
+// templateTileset returns the map tileset which is the same file as the template tileset provided.  When the map does
+// not use the tileset it is loaded, and added to the map after all existing tilesets.  The dir is the directory of the
+// template; which the tileset source is relative to.
+func (m *Map) templateTileset(ts *Tileset, dir string) (*Tileset, error) {
+	if ts.Source == "" {
+		log.WithError(ErrInvalidTemplate).Error("Map.templateTileset: template tileset has no source")
+		return nil, ErrInvalidTemplate
+	}
+
+	path := filepath.Join(dir, ts.Source)
+	for _, mts := range m.Tilesets {
+		if mts.Source != "" && filepath.Join(m.dir, mts.Source) == path {
+			return mts, nil
+		}
+	}
+
+	f, err := m.openFileFunc(path)
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("Map.templateTileset: could not open tileset")
+		return nil, err
+	}
+	loaded, err := readTilesetSource(f, path, filepath.Dir(path))
+	_ = f.Close()
+	if err != nil {
+		log.WithError(err).WithField("Filepath", path).Error("Map.templateTileset: could not read tileset")
+		return nil, err
+	}
+
+	loaded.Source = path
+	if rel, err := filepath.Rel(m.dir, path); err == nil {
+		loaded.Source = rel
+	}
+
+	loaded.FirstGID = 1
+	for _, mts := range m.Tilesets {
+		if next := mts.FirstGID + GID(mts.Tilecount); next > loaded.FirstGID {
+			loaded.FirstGID = next
+		}
+	}
+	m.Tilesets = append(m.Tilesets, loaded)
+
+	return loaded, nil
+}
@@ -0,0 +1,105 @@
+package tilepix_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+)
+
+func TestObject_Template(t *testing.T) {
+	for _, path := range []string{"testdata/templates.tmx", "testdata/templates.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			enemy := m.GetObjectByID(1)
+			if enemy.Name != "enemy" || enemy.Type != "Enemy" || enemy.Width != 16 || enemy.Height != 16 {
+				t.Errorf("Expected object fields from template, got %+v", enemy)
+			}
+			if enemy.GetType() != tilepix.EllipseObj {
+				t.Errorf("Expected ellipse from template, got %v", enemy.GetType())
+			}
+			if enemy.Props().Int("hp", 0) != 10 || enemy.Props().Float("speed", 0) != 1.5 {
+				t.Errorf("Expected properties from template, got %v", enemy.Properties)
+			}
+			// The instance is at 16,32 in Tiled, which is the top-left of the object.
+			if enemy.X != 16 || enemy.Y != 16 {
+				t.Errorf("Expected position from the instance, got %v,%v", enemy.X, enemy.Y)
+			}
+
+			boss := m.GetObjectByID(2)
+			if boss.Name != "boss" || boss.Type != "Enemy" {
+				t.Errorf("Expected name override, got %+v", boss)
+			}
+			props := boss.Props()
+			if props.Int("hp", 0) != 50 || props.Float("speed", 0) != 1.5 {
+				t.Errorf("Expected merged properties, got %v", props)
+			}
+			if props.Int("stats.hp", 0) != 99 || props.Int("stats.armor", 0) != 1 {
+				t.Errorf("Expected merged class members, got %v", props.Get("stats"))
+			}
+			if enemy.Props().Int("stats.hp", 0) != 5 {
+				t.Error("Expected template properties to be unchanged by instances")
+			}
+
+			crate := m.GetObjectByID(3)
+			if crate.Name != "crate" || crate.GID != 7 || crate.GetType() != tilepix.TileObj {
+				t.Errorf("Expected tile template using map tileset, got %+v", crate)
+			}
+
+			barrel := m.GetObjectByID(4)
+			if barrel.Name != "barrel" || barrel.GID != 21 {
+				t.Errorf("Expected tile template using added tileset, got %+v", barrel)
+			}
+			if len(m.Tilesets) != 2 || m.Tilesets[1].FirstGID != 20 {
+				t.Errorf("Expected template tileset to be added to map, got %v", m.Tilesets)
+			}
+		})
+	}
+}
+
+func TestObject_TemplateCached(t *testing.T) {
+	opened := make(map[string]int)
+	openFile := func(name string) (http.File, error) {
+		opened[filepath.ToSlash(name)]++
+		return os.Open(name)
+	}
+
+	f, err := os.Open("testdata/templates.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := tilepix.Read(f, "testdata", openFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := opened["testdata/templates/enemy.tx"]; n != 1 {
+		t.Errorf("Expected template to be read once, read %d times", n)
+	}
+}
+
+func TestObject_TemplateMissing(t *testing.T) {
+	openFile := func(name string) (http.File, error) {
+		if filepath.Ext(name) == ".tx" {
+			return nil, os.ErrNotExist
+		}
+		return os.Open(name)
+	}
+
+	f, err := os.Open("testdata/templates.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := tilepix.Read(f, "testdata", openFile); err == nil {
+		t.Error("Expected error for missing template")
+	}
+}
@@ -0,0 +1,10 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" tiledversion="1.10.2" name="collection" tilewidth="32" tileheight="48" tilecount="2" columns="0">
+ <grid orientation="orthogonal" width="1" height="1"/>
+ <tile id="0">
+  <image source="collection/tree.png" width="32" height="48"/>
+ </tile>
+ <tile id="3">
+  <image source="collection/rock.png" width="16" height="16"/>
+ </tile>
+</tileset>
@@ -0,0 +1,143 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _____           _
+ |_   _|___ __ __| |_
+   | | / -_)\ \ /|  _|
+   |_| \___|/_\_\ \__|
+*/
+
+// Text is a TMX file structure holding the text of a Tiled text object, and how it should be displayed.
+type Text struct {
+	Text       string `xml:",chardata"`
+	FontFamily string `xml:"fontfamily,attr"`
+	// PixelSize is the size of the font in pixels.
+	PixelSize int `xml:"pixelsize,attr"`
+	// Wrap is whether lines are wrapped to the width of the object.
+	Wrap bool `xml:"wrap,attr"`
+	// Color is the colour of the text, in the format "#AARRGGBB" or "#RRGGBB".
+	Color     string `xml:"color,attr"`
+	Bold      bool   `xml:"bold,attr"`
+	Italic    bool   `xml:"italic,attr"`
+	Underline bool   `xml:"underline,attr"`
+	Strikeout bool   `xml:"strikeout,attr"`
+	Kerning   bool   `xml:"kerning,attr"`
+	// HAlign is the horizontal alignment of the text; one of "left", "center", "right" or "justify".
+	HAlign string `xml:"halign,attr"`
+	// VAlign is the vertical alignment of the text; one of "top", "center" or "bottom".
+	VAlign string `xml:"valign,attr"`
+}
+
+// GetColor returns the colour of the text.  Text without a valid colour is black.
+func (t *Text) GetColor() pixel.RGBA {
+	c, err := parseColor(t.Color)
+	if err != nil {
+		return pixel.RGB(0, 0, 0)
+	}
+	return pixel.ToRGBA(c)
+}
+
+func (t *Text) String() string {
+	return fmt.Sprintf("Text{'%s', Font: %s %dpx}", t.Text, t.FontFamily, t.PixelSize)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that attributes which Tiled omits when set to their default are
+// initialised correctly.
+func (t *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tmxText Text
+	v := tmxText{
+		FontFamily: "sans-serif",
+		PixelSize:  16,
+		Color:      "#000000",
+		Kerning:    true,
+		HAlign:     "left",
+		VAlign:     "top",
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*t = Text(v)
+	return nil
+}
+
+// draw renders the text within the bounds provided using the atlas, and then transforms it with the matrix; the atlas is
+// scaled so that its line height matches the pixel size of the text.
+func (t *Text) draw(target pixel.Target, atlas *text.Atlas, bounds pixel.Rect, mat pixel.Matrix) {
+	scale := 1.0
+	if t.PixelSize > 0 && atlas.LineHeight() > 0 {
+		scale = float64(t.PixelSize) / atlas.LineHeight()
+	}
+
+	txt := text.New(pixel.ZV, atlas)
+	txt.Color = t.GetColor()
+
+	// Layout is calculated in the unscaled space of the atlas, from the top-left of the bounds.
+	width, height := bounds.W()/scale, bounds.H()/scale
+	lines := t.lines(txt, width)
+
+	vOffset := 0.0
+	free := height - float64(len(lines))*atlas.LineHeight()
+	switch t.VAlign {
+	case "center":
+		vOffset = free / 2
+	case "bottom":
+		vOffset = free
+	}
+
+	for i, line := range lines {
+		hOffset := 0.0
+		switch t.HAlign {
+		case "center":
+			hOffset = (width - txt.BoundsOf(line).W()) / 2
+		case "right":
+			hOffset = width - txt.BoundsOf(line).W()
+		}
+
+		txt.Dot = pixel.V(hOffset, -(vOffset + atlas.Ascent() + float64(i)*atlas.LineHeight()))
+		if _, err := txt.WriteString(line); err != nil {
+			log.WithError(err).Error("Text.draw: could not write text")
+			return
+		}
+	}
+
+	txt.Draw(target, pixel.IM.Scaled(pixel.ZV, scale).Moved(bounds.Min.Add(pixel.V(0, bounds.H()))).Chained(mat))
+}
+
+// lines splits the text into lines, wrapping words to the width provided when the text should be wrapped.
+func (t *Text) lines(txt *text.Text, width float64) []string {
+	lines := strings.Split(t.Text, "\n")
+	if !t.Wrap {
+		return lines
+	}
+
+	var wrapped []string
+	for _, line := range lines {
+		current := ""
+		for _, word := range strings.Fields(line) {
+			candidate := word
+			if current != "" {
+				candidate = current + " " + word
+			}
+
+			if current != "" && txt.BoundsOf(candidate).W() > width {
+				wrapped = append(wrapped, current)
+				candidate = word
+			}
+			current = candidate
+		}
+		wrapped = append(wrapped, current)
+	}
+
+	return wrapped
+}
@@ -0,0 +1,56 @@
+package tilepix
+
+import (
+	"testing"
+
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/text"
+)
+
+func TestText_String(t *testing.T) {
+	txt := &Text{Text: "Hello", FontFamily: "sans-serif", PixelSize: 16}
+	if got, want := txt.String(), "Text{'Hello', Font: sans-serif 16px}"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestText_lines(t *testing.T) {
+	atlasText := text.New(pixel.ZV, text.Atlas7x13)
+	// Each glyph of the 7x13 atlas is 7 pixels wide, so 10 glyphs fit within 70 pixels.
+	const width = 70
+
+	tests := []struct {
+		name string
+		text Text
+		want []string
+	}{
+		{
+			name: "No wrap",
+			text: Text{Text: "one two three four\nfive"},
+			want: []string{"one two three four", "five"},
+		},
+		{
+			name: "Wrap",
+			text: Text{Text: "one two three four\nfive", Wrap: true},
+			want: []string{"one two", "three four", "five"},
+		},
+		{
+			name: "Long word",
+			text: Text{Text: "extraordinarily long", Wrap: true},
+			want: []string{"extraordinarily", "long"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.text.lines(atlasText, width)
+			if len(got) != len(tt.want) {
+				t.Fatalf("lines() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("lines() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/gopxl/pixel"
 )
@@ -16,27 +18,114 @@ import (
 
 // Tile is a TMX file structure which holds a Tiled tile.
 type Tile struct {
-	ID    ID     `xml:"id,attr"`
-	Image *Image `xml:"image"`
+	ID ID `xml:"id,attr"`
+	// Type is the class of the tile; Tiled 1.9 saved this in the "class" attribute, which is also read into Type.
+	Type string `xml:"type,attr"`
+	// Probability is the chance of the tile being chosen by the terrain and random mode tools of Tiled, relative to
+	// other tiles.
+	Probability float64    `xml:"probability,attr"`
+	Properties  Properties `xml:"properties>property"`
+	Image       *Image     `xml:"image"`
 	// ObjectGroup is set if objects have been added to individual sprites in Tiled.
 	ObjectGroup *ObjectGroup `xml:"objectgroup,omitempty"`
+	// Animation holds the frames of an animated tile, in the order they are shown.
+	Animation []*Frame `xml:"animation>frame"`
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// FrameAt returns the ID of the tile shown at the time provided, measured from the start of the animation.  Animations
+// loop, and a tile which is not animated always shows itself.
+func (t *Tile) FrameAt(at time.Duration) ID {
+	return frameAt(t.ID, t.Animation, at)
+}
+
+// Props returns the custom properties of the tile.
+func (t *Tile) Props() Properties {
+	return t.Properties
+}
+
 func (t *Tile) String() string {
 	return fmt.Sprintf("Tile{ID: %d}", t.ID)
 }
 
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the tile.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (t *Tile) UnmarshalProperties(v interface{}) error {
+	return t.Properties.Unmarshal(v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler, so that attributes which Tiled omits when set to their default are
+// initialised correctly.
+func (t *Tile) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tile Tile
+	v := struct {
+		tile
+		Class string `xml:"class,attr"`
+	}{tile: tile{Probability: 1}}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*t = Tile(v.tile)
+	if t.Type == "" {
+		t.Type = v.Class
+	}
+	return nil
+}
+
 func (t *Tile) setParent(m *Map) {
 	t.parentMap = m
 
+	for _, p := range t.Properties {
+		p.setParent(m)
+	}
+
 	if t.Image != nil {
 		t.Image.setParent(m)
 	}
 }
 
+// Frame is a TMX file structure which holds a single frame of an animated tile.
+type Frame struct {
+	// TileID is the ID of the tile shown, within the tileset of the animated tile.
+	TileID ID `xml:"tileid,attr"`
+	// Duration is how long the frame is shown for, in milliseconds.
+	Duration int `xml:"duration,attr"`
+}
+
+func (f *Frame) String() string {
+	return fmt.Sprintf("Frame{TileID: %d, Duration: %dms}", f.TileID, f.Duration)
+}
+
+// frameAt returns the ID of the tile shown by the animation at the time provided; id is returned if there are no frames.
+func frameAt(id ID, frames []*Frame, at time.Duration) ID {
+	var total time.Duration
+	for _, f := range frames {
+		total += time.Duration(f.Duration) * time.Millisecond
+	}
+	if total <= 0 {
+		if len(frames) > 0 {
+			return frames[0].TileID
+		}
+		return id
+	}
+
+	at %= total
+	if at < 0 {
+		at += total
+	}
+	for _, f := range frames {
+		at -= time.Duration(f.Duration) * time.Millisecond
+		if at < 0 {
+			return f.TileID
+		}
+	}
+
+	return frames[len(frames)-1].TileID
+}
+
 // DecodedTile is a convenience struct, which stores the decoded data from a Tile.
 type DecodedTile struct {
 	ID             ID
@@ -44,13 +133,21 @@ type DecodedTile struct {
 	HorizontalFlip bool
 	VerticalFlip   bool
 	DiagonalFlip   bool
-	Nil            bool
+	// RotateHex120 is whether the tile is rotated 120 degrees clockwise; this is only used on hexagonal maps, where
+	// DiagonalFlip instead rotates the tile 60 degrees clockwise.
+	RotateHex120 bool
+	Nil          bool
 
 	sprite    *pixel.Sprite
 	transform pixel.Matrix
+	// animation holds the frames of the tile when it is animated, and frame the ID of the tile currently shown.
+	animation []*Frame
+	frame     ID
 
 	// parentMap is the map which contains this object
 	parentMap *Map
+	// parentLayer is the layer which contains this tile, it is not set for tiles belonging to objects.
+	parentLayer *TileLayer
 }
 
 // Draw will draw the tile to the target provided.  This will calculate the sprite from the provided tileset and set the
@@ -64,27 +161,45 @@ func (t *DecodedTile) Draw(ind, columns, numRows int, ts *Tileset, target pixel.
 		t.setSprite(columns, numRows, ts)
 
 		// Calculate the framing for the tile within its tileset's source image
-		pos := t.Position(ind, ts)
-		transform := pixel.IM.Moved(pos)
-		if t.DiagonalFlip {
-			transform = transform.Rotated(pos, math.Pi/2)
-			transform = transform.ScaledXY(pos, pixel.V(1, -1))
-		}
-		if t.HorizontalFlip {
-			transform = transform.ScaledXY(pos, pixel.V(-1, 1))
-		}
-		if t.VerticalFlip {
-			transform = transform.ScaledXY(pos, pixel.V(1, -1))
-		}
-		t.transform = transform
+		t.transform = t.flipMatrix().Moved(t.Position(ind, ts))
 	}
 	t.sprite.Draw(target, t.transform.Moved(offset))
 }
 
-// Position returns the relative game position.
+// Frame returns the ID of the tile currently shown, within the tileset.  This is the ID of the tile unless it is
+// animated.
+func (t *DecodedTile) Frame() ID {
+	if t.animation == nil {
+		return t.ID
+	}
+	return t.frame
+}
+
+// Properties returns the custom properties of the tile, as set on the tile in its tileset.  Nil is returned for nil
+// tiles and tiles without properties.
+func (t *DecodedTile) Properties() Properties {
+	if t.IsNil() || t.Tileset == nil {
+		return nil
+	}
+
+	tile := t.Tileset.GetTile(t.ID)
+	if tile == nil {
+		return nil
+	}
+	return tile.Properties
+}
+
+// Position returns the game position of the centre of the tile.  As in Tiled, tiles are aligned to the bottom-left of
+// their cell; tiles larger than the cells of the map extend up and to the right.  The cells of isometric maps are the
+// bounding box of the diamond shaped tile.  The tile offset of the tileset is included.  For tiles belonging to objects,
+// which have no layer, ind is the index of a cell of the map.
 func (t DecodedTile) Position(ind int, ts *Tileset) pixel.Vec {
-	gamePos := indexToGamePos(ind, t.parentMap.Width, t.parentMap.Height)
-	return gamePos.ScaledXY(pixel.V(float64(ts.TileWidth), float64(ts.TileHeight))).Add(pixel.V(float64(ts.TileWidth), float64(ts.TileHeight)).Scaled(0.5))
+	x, y := ind%t.parentMap.Width, ind/t.parentMap.Width
+	if t.parentLayer != nil {
+		x, y = t.parentLayer.indexToTile(ind)
+	}
+	pos := t.parentMap.tileCell(x, y).Min.Add(ts.tileSize(t.Frame()).Scaled(0.5))
+	return pos.Add(ts.tileOffset())
 }
 
 func (t *DecodedTile) String() string {
@@ -101,6 +216,62 @@ func (t *DecodedTile) setParent(m *Map) {
 	t.parentMap = m
 }
 
+// flipMatrix returns the matrix which applies the flips of the tile, about its centre.
+func (t *DecodedTile) flipMatrix() pixel.Matrix {
+	if t.parentMap != nil && t.parentMap.Orientation == "hexagonal" {
+		return t.hexagonalFlipMatrix()
+	}
+
+	transform := pixel.IM
+	if t.DiagonalFlip {
+		transform = transform.Rotated(pixel.ZV, math.Pi/2)
+		transform = transform.ScaledXY(pixel.ZV, pixel.V(1, -1))
+	}
+	if t.HorizontalFlip {
+		transform = transform.ScaledXY(pixel.ZV, pixel.V(-1, 1))
+	}
+	if t.VerticalFlip {
+		transform = transform.ScaledXY(pixel.ZV, pixel.V(1, -1))
+	}
+
+	return transform
+}
+
+// hexagonalFlipMatrix returns the matrix which applies the flips and rotations of a tile on a hexagonal map, about its
+// centre.  The tile is flipped before being rotated clockwise.
+func (t *DecodedTile) hexagonalFlipMatrix() pixel.Matrix {
+	scale := pixel.V(1, 1)
+	if t.HorizontalFlip {
+		scale.X = -1
+	}
+	if t.VerticalFlip {
+		scale.Y = -1
+	}
+
+	angle := 0.0
+	if t.DiagonalFlip {
+		angle -= math.Pi / 3
+	}
+	if t.RotateHex120 {
+		angle -= 2 * math.Pi / 3
+	}
+
+	return pixel.IM.ScaledXY(pixel.ZV, scale).Rotated(pixel.ZV, angle)
+}
+
+// setFrame sets the frame of an animated tile to that shown at the time provided, and returns whether the frame
+// changed.  The sprite is recalculated when next drawn.
+func (t *DecodedTile) setFrame(at time.Duration) bool {
+	frame := frameAt(t.ID, t.animation, at)
+	if frame == t.frame {
+		return false
+	}
+
+	t.frame = frame
+	t.sprite = nil
+	return true
+}
+
 func (t *DecodedTile) setSprite(columns, numRows int, ts *Tileset) {
 	if t.IsNil() {
 		return
@@ -108,12 +279,6 @@ func (t *DecodedTile) setSprite(columns, numRows int, ts *Tileset) {
 
 	if t.sprite == nil {
 		// Calculate the framing for the tile within its tileset's source image
-		x, y := tileIDToCoord(t.ID, columns, numRows)
-		iX := float64(x)*float64(ts.TileWidth) + float64(ts.Margin+ts.Spacing*(x-1))
-		fX := iX + float64(ts.TileWidth)
-		iY := float64(y)*float64(ts.TileHeight) + float64(ts.Margin+ts.Spacing*(y-1))
-		fY := iY + float64(ts.TileHeight)
-
-		t.sprite = pixel.NewSprite(ts.setSprite(), pixel.R(iX, iY, fX, fY))
+		t.sprite = pixel.NewSprite(ts.setSprite(), ts.tileFrame(t.Frame()))
 	}
 }
@@ -0,0 +1,133 @@
+package tilepix_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+)
+
+func TestDecodedTile_Properties(t *testing.T) {
+	for _, path := range []string{"testdata/tile_properties.tmx", "testdata/tile_properties.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lava := m.Tilesets[0].GetTile(0)
+			if lava == nil || lava.Type != "lava" || lava.Probability != 1 {
+				t.Errorf("Expected lava tile with default probability, got %+v", lava)
+			}
+			water := m.Tilesets[0].GetTile(4)
+			if water == nil || water.Type != "water" || water.Probability != 0.5 {
+				t.Errorf("Expected water tile, got %+v", water)
+			}
+
+			l := m.GetTileLayerByName("ground")
+			if got := l.TileAt(0, 0).Properties().Int("damage", 0); got != 5 {
+				t.Errorf("Properties().Int() = %v, want 5", got)
+			}
+			props := l.TileAt(1, 0).Properties()
+			if props.String("terrain", "") != "water" || !props.Bool("swimmable", false) {
+				t.Errorf("Properties() = %v, want water properties", props)
+			}
+			if props := l.TileAt(2, 0).Properties(); props != nil {
+				t.Errorf("Properties() = %v, want nil for tile without data", props)
+			}
+			if props := tilepix.NilTile.Properties(); props != nil {
+				t.Errorf("Properties() = %v, want nil for nil tile", props)
+			}
+		})
+	}
+}
+
+func TestTileset_Collection(t *testing.T) {
+	for _, path := range []string{"testdata/collection.tmx", "testdata/collection.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := m.GetTileLayerByName("scenery")
+			ts := m.Tilesets[0]
+			// Tiles are aligned to the bottom-left of their cell.
+			if got, want := l.DecodedTiles[12].Position(12, ts), pixel.V(16, 24); got != want {
+				t.Errorf("Tree Position() = %v, want %v", got, want)
+			}
+			if got, want := l.DecodedTiles[15].Position(15, ts), pixel.V(56, 8); got != want {
+				t.Errorf("Rock Position() = %v, want %v", got, want)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Fatalf("Could not draw map: %v", err)
+			}
+
+			tile, err := m.GetObjectByName("rock")[0].GetTile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tile.ID != 3 {
+				t.Errorf("Expected rock tile object, got %v", tile)
+			}
+		})
+	}
+}
+
+func TestTileset_TileOffset(t *testing.T) {
+	for _, path := range []string{"testdata/tileoffset.tmx", "testdata/tileoffset.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts := m.Tilesets[0]
+			if ts.ObjectAlignment != "bottom" || ts.TileOffset == nil || ts.TileOffset.X != 2 || ts.TileOffset.Y != -4 {
+				t.Errorf("Unexpected tileset alignment or offset %s %v", ts.ObjectAlignment, ts.TileOffset)
+			}
+			if ts.Grid == nil || ts.Grid.Orientation != "isometric" || ts.Grid.Width != 16 || ts.Grid.Height != 8 {
+				t.Errorf("Unexpected tileset grid %v", ts.Grid)
+			}
+
+			l := m.GetTileLayerByName("ground")
+			if got, want := l.DecodedTiles[0].Position(0, ts), pixel.V(10, 60); got != want {
+				t.Errorf("Position() = %v, want %v", got, want)
+			}
+
+			// The tile object is positioned by its bottom-centre, the rectangle by its top-left.
+			tile := m.GetObjectByName("tile")[0]
+			if got, want := pixel.V(tile.X, tile.Y), pixel.V(26, 36); got != want {
+				t.Errorf("Tile object position = %v, want %v", got, want)
+			}
+			rect := m.GetObjectByName("rect")[0]
+			if got, want := pixel.V(rect.X, rect.Y), pixel.V(32, 16); got != want {
+				t.Errorf("Rectangle object position = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodedTile_PositionObject(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/tile_objects.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tile, err := m.GetObjectByName("white")[0].GetTile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tile of an object has no layer, so the index is of a cell of the 4x4 map; index 5 is the cell at 1,1.
+	if got, want := tile.Position(5, tile.Tileset), pixel.V(24, 40); got != want {
+		t.Errorf("Position() = %v, want %v", got, want)
+	}
+}
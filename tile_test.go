@@ -2,6 +2,7 @@ package tilepix
 
 import (
 	"testing"
+	"time"
 )
 
 func TestDecodedTile_String(t1 *testing.T) {
@@ -43,3 +44,36 @@ func TestDecodedTile_String(t1 *testing.T) {
 		})
 	}
 }
+
+func TestTile_FrameAt(t *testing.T) {
+	tile := &Tile{
+		ID: 4,
+		Animation: []*Frame{
+			{TileID: 0, Duration: 100},
+			{TileID: 1, Duration: 200},
+			{TileID: 2, Duration: 100},
+		},
+	}
+
+	tests := []struct {
+		at   time.Duration
+		want ID
+	}{
+		{at: 0, want: 0},
+		{at: 99 * time.Millisecond, want: 0},
+		{at: 100 * time.Millisecond, want: 1},
+		{at: 299 * time.Millisecond, want: 1},
+		{at: 350 * time.Millisecond, want: 2},
+		{at: 400 * time.Millisecond, want: 0},
+		{at: 1150 * time.Millisecond, want: 2},
+	}
+	for _, tt := range tests {
+		if got := tile.FrameAt(tt.at); got != tt.want {
+			t.Errorf("FrameAt(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	if got := (&Tile{ID: 4}).FrameAt(time.Second); got != 4 {
+		t.Errorf("FrameAt() = %v, want 4 for tile without animation", got)
+	}
+}
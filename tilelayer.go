@@ -1,8 +1,11 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"image/color"
+	"time"
 
 	"github.com/gopxl/pixel"
 	log "github.com/sirupsen/logrus"
@@ -18,75 +21,162 @@ import (
 
 // TileLayer is a TMX file structure which can hold any type of Tiled layer.
 type TileLayer struct {
-	Name       string      `xml:"name,attr"`
-	Opacity    float32     `xml:"opacity,attr"`
-	OffSetX    float64     `xml:"offsetx,attr"`
-	OffSetY    float64     `xml:"offsety,attr"`
-	Visible    bool        `xml:"visible,attr"`
-	Properties []*Property `xml:"properties>property"`
-	Data       Data        `xml:"data"`
+	Name       string     `xml:"name,attr"`
+	Opacity    float32    `xml:"opacity,attr"`
+	OffSetX    float64    `xml:"offsetx,attr"`
+	OffSetY    float64    `xml:"offsety,attr"`
+	Visible    bool       `xml:"visible,attr"`
+	TintColor  string     `xml:"tintcolor,attr"`
+	Properties Properties `xml:"properties>property"`
+	Data       Data       `xml:"data"`
+	// ParallaxX and ParallaxY are the factors the layer scrolls by relative to the camera, see Map.DrawAllParallax.
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
 	// DecodedTiles is the attribute you should use instead of `Data`.
-	// Tile entry at (x,y) is obtained using l.DecodedTiles[y*map.Width+x].
+	// Tile entry at (x,y) is obtained using l.DecodedTiles[(y-l.StartY)*l.Width+(x-l.StartX)], or with `TileAt`.
 	DecodedTiles []*DecodedTile
-	// Tileset is only set when the layer uses a single tileset and NilLayer is false.
+	// StartX and StartY are the tile co-ordinates of the first entry in DecodedTiles.  These are only non-zero for
+	// infinite maps, where they may be negative.
+	StartX int `xml:"-"`
+	StartY int `xml:"-"`
+	// Width and Height are the number of tiles held in DecodedTiles.  For infinite maps this is the smallest area
+	// which covers all chunks in the layer.
+	Width  int `xml:"-"`
+	Height int `xml:"-"`
+	// Tileset is only set when the layer uses a single tileset and NilLayer is false.  See Tilesets for layers which
+	// use multiple tilesets.
 	Tileset *Tileset
 	// Empty should be set when all entries of the layer are NilTile.
 	Empty bool
 
-	batch   *pixel.Batch
-	isDirty bool
-	static  bool
-
+	// batches holds a batch for each picture used by the layer, as a batch may only draw from a single picture.
+	// Tilesets have a picture each, unless they have been packed with Map.PackTilesets.
+	batches map[pixel.Picture]*pixel.Batch
+	// tilesets holds the tilesets used by the layer, in the order they appear in the map.
+	tilesets []*Tileset
+	isDirty  bool
+	static   bool
+	// animatedTiles holds the tiles of the layer which are animated.
+	animatedTiles []*DecodedTile
+	// mask is the colour mask the batches were last drawn with.
+	mask pixel.RGBA
+	// drawOrder holds the indices of DecodedTiles in the order they are drawn, it is calculated when first drawn.
+	drawOrder []int
+
+	// tint is the colour mask parsed from TintColor, it is nil when the layer is not tinted.  It is set when the layer is
+	// read and by SetTintColor, so that the tint is only parsed once.
+	tint *pixel.RGBA
+
+	// parentGroup is the group which contains this layer, it is nil for top-level layers.
+	parentGroup *GroupLayer
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
-// Batch returns the batch with the picture data from the tileset associated with this layer.
-func (l *TileLayer) Batch() (*pixel.Batch, error) {
-	if l.batch == nil {
-		log.Debug("TileLayer.Batch: batch not initialised, creating")
+// Bounds returns the area, in game co-ordinates, covered by the layer.
+func (l *TileLayer) Bounds() pixel.Rect {
+	if l.Width == 0 || l.Height == 0 {
+		return pixel.R(0, 0, 0, 0)
+	}
 
-		if l.Tileset == nil {
-			err := errors.New("cannot create sprite from nil tileset")
-			log.WithError(err).Error("TileLayer.Batch: layers' tileset is nil")
-			return nil, err
+	// The cells at the corners of the layer are at its extremes, whatever the orientation of the map.  On staggered
+	// and hexagonal maps the shifted rows or columns may extend further, so the cells beside the corners are included.
+	lastX, lastY := l.StartX+l.Width-1, l.StartY+l.Height-1
+	xs := []int{l.StartX, min(l.StartX+1, lastX), max(lastX-1, l.StartX), lastX}
+	ys := []int{l.StartY, min(l.StartY+1, lastY), max(lastY-1, l.StartY), lastY}
+	bounds := l.parentMap.tileCell(l.StartX, l.StartY)
+	for _, x := range xs {
+		for _, y := range ys {
+			bounds = bounds.Union(l.parentMap.tileCell(x, y))
 		}
+	}
+
+	return bounds
+}
 
-		pictureData := l.Tileset.setSprite()
-		l.batch = pixel.NewBatch(&pixel.TrianglesData{}, pictureData)
+// Batch returns the batch with the picture data from the tileset associated with this layer.  Layers which use
+// multiple tilesets have a single batch only when the tilesets have been packed with Map.PackTilesets, otherwise use
+// TilesetBatch to get the batch of each of Tilesets.
+func (l *TileLayer) Batch() (*pixel.Batch, error) {
+	ts := l.Tileset
+	if ts == nil && len(l.pictures()) == 1 {
+		ts = l.tilesets[0]
 	}
 
-	l.batch.Clear()
+	if ts == nil {
+		err := errors.New("cannot create sprite from nil tileset")
+		log.WithError(err).Error("TileLayer.Batch: layers' tileset is nil")
+		return nil, err
+	}
 
-	return l.batch, nil
+	return l.TilesetBatch(ts)
 }
 
-// Draw will use the TileLayers' batch to draw all tiles within the TileLayer to the target.
+// Draw will use the TileLayers' batch to draw all tiles within the TileLayer to the target.  Animated tiles are shown
+// at the time of the maps' clock, see Map.Update.  The layer is drawn with its effective opacity and tint, and hidden
+// layers are not drawn.
 func (l *TileLayer) Draw(target pixel.Target) error {
+	return l.DrawAt(target, l.parentMap.clock)
+}
+
+// DrawAt will draw the TileLayer to the target in the same way as Draw, with animated tiles shown at the time provided
+// rather than that of the maps' clock.
+func (l *TileLayer) DrawAt(target pixel.Target, at time.Duration) error {
+	if l.Empty {
+		// Nothing to draw; an empty layer has no tileset to create the batch from.
+		return nil
+	}
+	if !l.EffectiveVisible() {
+		return nil
+	}
+
+	// A change of opacity or tint, of the layer or a group containing it, must be drawn to the batch.
+	if mask := colorMask(l); mask != l.mask {
+		l.mask = mask
+		l.SetDirty(true)
+	}
+
+	// A change of frame must be drawn to the batch, even when the layer is static.
+	if l.updateFrames(at) {
+		l.SetDirty(true)
+	}
+
 	// Only draw if the layer is dirty.
 	if l.isDirty {
-		// Initialise the batch
-		if _, err := l.Batch(); err != nil {
-			log.WithError(err).Error("TileLayer.Draw: could not get batch")
-			return err
+		// Initialise the batches, a batch is shared by tilesets with the same picture
+		for _, ts := range l.Tilesets() {
+			if _, err := l.TilesetBatch(ts); err != nil {
+				log.WithError(err).Error("TileLayer.DrawAt: could not get batch")
+				return err
+			}
 		}
 
-		ts := l.Tileset
-		numRows := ts.Tilecount / ts.Columns
-
-		// Loop through each decoded tile
-		for tileIndex, tile := range l.DecodedTiles {
-			// The Y component of the offset is set in Tiled from top down, setting here to negative because we want
-			// from the bottom up.
-			layerOffset := pixel.V(l.OffSetX, -l.OffSetY)
-			tile.Draw(tileIndex, ts.Columns, numRows, ts, l.batch, layerOffset)
+		// Loop through each decoded tile, drawing it to the batch of its tileset.  Tiles are drawn in the same order as
+		// Tiled, so that where tiles overlap the same tile is on top.
+		if l.drawOrder == nil {
+			l.drawOrder = l.tileDrawOrder()
+		}
+		for _, tileIndex := range l.drawOrder {
+			tile := l.DecodedTiles[tileIndex]
+			if tile.IsNil() {
+				continue
+			}
+
+			// The offset includes those of any groups containing this layer.
+			layerOffset := l.EffectiveOffset()
+			ts := tile.Tileset
+			tile.Draw(tileIndex, ts.Columns, ts.numRows(), ts, l.batches[ts.picture], layerOffset)
 		}
 
-		// Batch is drawn to, layer is no longer dirty.
+		// Batches are drawn to, layer is no longer dirty.
 		l.SetDirty(false)
 	}
 
-	l.batch.Draw(target)
+	// Tiles are drawn picture by picture, so where tiles of different pictures overlap those of later tilesets are on
+	// top.
+	for _, pic := range l.pictures() {
+		l.batches[pic].Draw(target)
+	}
 
 	// Reset the dirty flag if the layer is not static
 	if !l.static {
@@ -96,6 +186,37 @@ func (l *TileLayer) Draw(target pixel.Target) error {
 	return nil
 }
 
+// EffectiveOffset returns the offset of the layer, including the offsets of all groups containing it.  The offset
+// is in game co-ordinates, so the Y component is inverted from that set in Tiled.
+func (l *TileLayer) EffectiveOffset() pixel.Vec {
+	return pixel.V(l.OffSetX, -l.OffSetY).Add(l.parentGroup.effectiveOffset())
+}
+
+// EffectiveOpacity returns the opacity of the layer, multiplied by the opacity of all groups containing it.
+func (l *TileLayer) EffectiveOpacity() float64 {
+	return float64(l.Opacity) * l.parentGroup.effectiveOpacity()
+}
+
+// EffectiveParallax returns the parallax factors of the layer, multiplied by those of all groups containing it.
+func (l *TileLayer) EffectiveParallax() pixel.Vec {
+	return pixel.V(l.ParallaxX, l.ParallaxY).ScaledXY(l.parentGroup.effectiveParallax())
+}
+
+// EffectiveTint returns the tint colour of the layer, multiplied by the tint of all groups containing it.
+func (l *TileLayer) EffectiveTint() pixel.RGBA {
+	return tintMask(l.tint).Mul(l.parentGroup.effectiveTint())
+}
+
+// EffectiveVisible returns whether the layer is visible; it is hidden if any group containing it is hidden.
+func (l *TileLayer) EffectiveVisible() bool {
+	return l.Visible && l.parentGroup.effectiveVisible()
+}
+
+// Props returns the custom properties of the layer.
+func (l *TileLayer) Props() Properties {
+	return l.Properties
+}
+
 // SetDirty will update the TileLayers' `dirty` property.  If true, this will cause the TileLayers' batch be cleared and
 // re-drawn next time `TileLayer.Draw` is called.
 func (l *TileLayer) SetDirty(newVal bool) {
@@ -110,13 +231,98 @@ func (l *TileLayer) SetStatic(newVal bool) {
 	l.static = newVal
 }
 
+// SetOpacity sets the opacity of the layer, from 0 for transparent to 1 for opaque.
+func (l *TileLayer) SetOpacity(opacity float64) {
+	l.Opacity = float32(opacity)
+}
+
+// SetTintColor sets the tint colour of the layer, which is multiplied with the colour of the tiles drawn.  A nil
+// colour removes the tint.
+func (l *TileLayer) SetTintColor(c color.Color) {
+	l.TintColor = formatColor(c)
+	l.tint = parseTint(l.TintColor)
+}
+
+// SetVisible sets whether the layer is drawn.
+func (l *TileLayer) SetVisible(visible bool) {
+	l.Visible = visible
+}
+
+// TilesetBatch returns the batch with the picture data of the tileset provided, which tiles of the layer from that
+// tileset are drawn to.  Tilesets packed with Map.PackTilesets share a batch.  The batch is cleared, and tiles are
+// drawn to it with the colour mask of the layer's effective opacity and tint.
+func (l *TileLayer) TilesetBatch(ts *Tileset) (*pixel.Batch, error) {
+	if ts == nil {
+		err := errors.New("cannot create sprite from nil tileset")
+		log.WithError(err).Error("TileLayer.TilesetBatch: tileset is nil")
+		return nil, err
+	}
+
+	pic := ts.setSprite()
+	batch, ok := l.batches[pic]
+	if !ok {
+		log.WithField("Tileset", ts.Name).Debug("TileLayer.TilesetBatch: batch not initialised, creating")
+
+		if l.batches == nil {
+			l.batches = make(map[pixel.Picture]*pixel.Batch)
+		}
+		batch = pixel.NewBatch(&pixel.TrianglesData{}, pic)
+		l.batches[pic] = batch
+	}
+
+	batch.Clear()
+	batch.SetColorMask(colorMask(l))
+
+	return batch, nil
+}
+
+// Tilesets returns the tilesets used by tiles of the layer, in the order they appear in the map.
+func (l *TileLayer) Tilesets() []*Tileset {
+	return l.tilesets
+}
+
+// TileAt returns the DecodedTile at the tile co-ordinates provided, where (0,0) is the top-left tile as in Tiled.  If
+// the co-ordinates are outside of the layer, NilTile is returned.
+func (l *TileLayer) TileAt(x, y int) *DecodedTile {
+	x, y = x-l.StartX, y-l.StartY
+	if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+		return NilTile
+	}
+
+	return l.DecodedTiles[y*l.Width+x]
+}
+
+// TileAtPosition returns the DecodedTile which covers the game position provided.  If the position is outside of the
+// layer, NilTile is returned.
+func (l *TileLayer) TileAtPosition(pos pixel.Vec) *DecodedTile {
+	return l.TileAt(l.parentMap.WorldToTile(pos))
+}
+
 func (l *TileLayer) String() string {
 	return fmt.Sprintf("TileLayer{Name: '%s', Properties: %v, TileCount: %d}", l.Name, l.Properties, len(l.DecodedTiles))
 }
 
-func (l *TileLayer) decode(width, height int) ([]GID, error) {
-	log.WithField("Encoding", l.Data.Encoding).Debug("TileLayer.decode: determining encoding")
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the layer.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (l *TileLayer) UnmarshalProperties(v interface{}) error {
+	return l.Properties.Unmarshal(v)
+}
 
+// UnmarshalXML implements xml.Unmarshaler, defaulting any attributes Tiled omits.
+func (l *TileLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tileLayer TileLayer
+	var v tileLayer
+	setLayerDefaults(&v.Opacity, &v.Visible, &v.ParallaxX, &v.ParallaxY)
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*l = TileLayer(v)
+	l.tint = parseTint(l.TintColor)
+	return nil
+}
+
+func (l *TileLayer) decode(width, height int, infinite bool) ([]GID, error) {
 	l.SetStatic(true)
 	l.SetDirty(true)
 
@@ -124,77 +330,147 @@ func (l *TileLayer) decode(width, height int) ([]GID, error) {
 		l.Tileset.setSprite()
 	}
 
-	switch l.Data.Encoding {
-	case "csv":
-		return l.decodeLayerCSV(width, height)
-	case "base64":
-		return l.decodeLayerBase64(width, height)
-	case "":
-		// XML "encoding"
-		return l.decodeLayerXML(width, height)
+	if infinite {
+		return l.decodeChunks()
 	}
 
-	log.WithError(ErrUnknownEncoding).Error("TileLayer.decode: unrecognised encoding")
-	return nil, ErrUnknownEncoding
+	l.Width, l.Height = width, height
+
+	return l.Data.decode(width, height)
 }
 
-func (l *TileLayer) decodeLayerXML(width, height int) ([]GID, error) {
-	if len(l.Data.DataTiles) != width*height {
-		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length datatiles": len(l.Data.DataTiles), "W*H": width * height}).Error("TileLayer.decodeLayerXML: data length mismatch")
-		return nil, ErrInvalidDecodedDataLen
+// decodeChunks will decode all chunks of an infinite layer into a single slice of GIDs, covering the smallest
+// rectangle which contains every chunk.  The layers' extent is set from that rectangle.
+func (l *TileLayer) decodeChunks() ([]GID, error) {
+	log.WithField("Chunk count", len(l.Data.Chunks)).Debug("TileLayer.decodeChunks: decoding chunks")
+
+	if len(l.Data.Chunks) == 0 {
+		return nil, nil
 	}
 
-	gids := make([]GID, len(l.Data.DataTiles))
-	for i := 0; i < len(gids); i++ {
-		gids[i] = l.Data.DataTiles[i].GID
+	minX, minY := l.Data.Chunks[0].X, l.Data.Chunks[0].Y
+	maxX, maxY := minX, minY
+	for _, c := range l.Data.Chunks {
+		minX = min(minX, c.X)
+		minY = min(minY, c.Y)
+		maxX = max(maxX, c.X+c.Width)
+		maxY = max(maxY, c.Y+c.Height)
 	}
 
-	return gids, nil
-}
+	l.StartX, l.StartY = minX, minY
+	l.Width, l.Height = maxX-minX, maxY-minY
 
-func (l *TileLayer) decodeLayerCSV(width, height int) ([]GID, error) {
-	gids, err := l.Data.decodeCSV()
-	if err != nil {
-		log.WithError(err).Error("TileLayer.decodeLayerCSV: could not decode CSV")
-		return nil, err
-	}
+	gids := make([]GID, l.Width*l.Height)
+	for _, c := range l.Data.Chunks {
+		chunkGIDs, err := c.decode(&l.Data)
+		if err != nil {
+			log.WithError(err).WithField("Chunk", c).Error("TileLayer.decodeChunks: could not decode chunk")
+			return nil, err
+		}
 
-	if len(gids) != width*height {
-		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length GIDSs": len(gids), "W*H": width * height}).Error("TileLayer.decodeLayerCSV: data length mismatch")
-		return nil, ErrInvalidDecodedDataLen
+		for y := 0; y < c.Height; y++ {
+			for x := 0; x < c.Width; x++ {
+				gids[(c.Y-minY+y)*l.Width+(c.X-minX+x)] = chunkGIDs[y*c.Width+x]
+			}
+		}
 	}
 
 	return gids, nil
 }
 
-func (l *TileLayer) decodeLayerBase64(width, height int) ([]GID, error) {
-	dataBytes, err := l.Data.decodeBase64()
-	if err != nil {
-		log.WithError(err).Error("TileLayer.decodeLayerBase64: could not decode base64")
-		return nil, err
+// indexToTile will convert an index in DecodedTiles to the tile co-ordinates it represents.
+func (l *TileLayer) indexToTile(idx int) (x, y int) {
+	return l.StartX + idx%l.Width, l.StartY + idx/l.Width
+}
+
+// pictures returns the pictures of the tilesets used by the layer, in the order of the tilesets.
+func (l *TileLayer) pictures() []pixel.Picture {
+	var pics []pixel.Picture
+	seen := make(map[pixel.Picture]bool)
+	for _, ts := range l.tilesets {
+		pic := ts.setSprite()
+		if !seen[pic] {
+			seen[pic] = true
+			pics = append(pics, pic)
+		}
 	}
 
-	if len(dataBytes) != width*height*4 {
-		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length databytes": len(dataBytes), "W*H": width * height}).Error("TileLayer.decodeLayerBase64: data length mismatch")
-		return nil, ErrInvalidDecodedDataLen
+	return pics
+}
+
+// resetSprites clears the sprites of all tiles and the batches of the layer, so that they are recalculated when the
+// layer is next drawn.
+func (l *TileLayer) resetSprites() {
+	for _, t := range l.DecodedTiles {
+		t.sprite = nil
 	}
+	l.batches = nil
+	l.SetDirty(true)
+}
 
-	gids := make([]GID, width*height)
+// tileDrawOrder returns the indices of DecodedTiles in the order Tiled draws them.  Tiles of orthogonal maps are drawn
+// in the render order of the map.  Those of other orientations are drawn row by row from the top-left, so that tiles
+// nearer the bottom of the map are drawn over those behind them; on maps where columns are staggered the columns which
+// are not shifted down are drawn first in each row.
+func (l *TileLayer) tileDrawOrder() []int {
+	order := make([]int, 0, len(l.DecodedTiles))
+
+	m := l.parentMap
+	switch m.Orientation {
+	case "staggered", "hexagonal":
+		p := m.staggerParams()
+		for y := 0; y < l.Height; y++ {
+			if !p.staggerX {
+				for x := 0; x < l.Width; x++ {
+					order = append(order, y*l.Width+x)
+				}
+				continue
+			}
+
+			for _, shifted := range []bool{false, true} {
+				for x := 0; x < l.Width; x++ {
+					if p.doStagger(l.StartX+x) == shifted {
+						order = append(order, y*l.Width+x)
+					}
+				}
+			}
+		}
+	case "isometric":
+		for i := range l.DecodedTiles {
+			order = append(order, i)
+		}
+	default:
+		left := m.RenderOrder == "left-down" || m.RenderOrder == "left-up"
+		up := m.RenderOrder == "right-up" || m.RenderOrder == "left-up"
+		for row := 0; row < l.Height; row++ {
+			y := row
+			if up {
+				y = l.Height - 1 - row
+			}
+			for col := 0; col < l.Width; col++ {
+				x := col
+				if left {
+					x = l.Width - 1 - col
+				}
+				order = append(order, y*l.Width+x)
+			}
+		}
+	}
 
-	j := 0
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			gid := GID(dataBytes[j]) +
-				GID(dataBytes[j+1])<<8 +
-				GID(dataBytes[j+2])<<16 +
-				GID(dataBytes[j+3])<<24
-			j += 4
+	return order
+}
 
-			gids[y*width+x] = gid
+// updateFrames sets the animated tiles of the layer to the frames shown at the time provided, and returns whether any
+// frame changed.
+func (l *TileLayer) updateFrames(at time.Duration) bool {
+	changed := false
+	for _, t := range l.animatedTiles {
+		if t.setFrame(at) {
+			changed = true
 		}
 	}
 
-	return gids, nil
+	return changed
 }
 
 func (l *TileLayer) setParent(m *Map) {
@@ -0,0 +1,156 @@
+package tilepix_test
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/bcvery1/tilepix"
+	"github.com/gopxl/pixel"
+	"github.com/gopxl/pixel/pixelgl"
+)
+
+func TestTileLayer_Infinite(t *testing.T) {
+	for _, path := range []string{
+		"testdata/infinite-csv.tmx",
+		"testdata/infinite-base64.tmx",
+		"testdata/infinite-gzip.tmx",
+		"testdata/infinite-zlib.tmx",
+	} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := m.GetTileLayerByName("Tile Layer 1")
+			if l.StartX != -16 || l.StartY != -16 || l.Width != 48 || l.Height != 32 {
+				t.Fatalf("Unexpected layer extent: start %d,%d size %dx%d", l.StartX, l.StartY, l.Width, l.Height)
+			}
+
+			if tile := l.TileAt(-16, -16); !tile.IsNil() {
+				t.Errorf("Expected nil tile at -16,-16, got %v", tile)
+			}
+			if tile := l.TileAt(-15, -16); tile.IsNil() || tile.ID != 14 {
+				t.Errorf("Expected tile 14 at -15,-16, got %v", tile)
+			}
+			if tile := l.TileAt(5, 5); !tile.IsNil() {
+				t.Errorf("Expected nil tile outside of chunks, got %v", tile)
+			}
+			if tile := l.TileAtPosition(pixel.V(321, 260)); tile.IsNil() || tile.ID != 3 {
+				t.Errorf("Expected tile 3 at position, got %v", tile)
+			}
+
+			if got, want := m.Bounds(), pixel.R(-256, 64, 512, 576); got != want {
+				t.Errorf("Map.Bounds() = %v, want %v", got, want)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Errorf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
+
+func TestMap_WorldToTile(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/infinite-csv.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tile := range [][2]int{{0, 0}, {-3, -7}, {20, 3}, {29, 19}} {
+		x, y := m.WorldToTile(m.TileToWorld(tile[0], tile[1]))
+		if x != tile[0] || y != tile[1] {
+			t.Errorf("Round trip of %v gave %d,%d", tile, x, y)
+		}
+	}
+}
+
+func TestTileLayer_Animation(t *testing.T) {
+	for _, path := range []string{"testdata/animated.tmx", "testdata/animated.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tile := m.Tilesets[0].GetTile(0)
+			if tile == nil || len(tile.Animation) != 3 || tile.Animation[1].TileID != 1 || tile.Animation[1].Duration != 200 {
+				t.Fatalf("Expected animated tile, got %v", tile)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := m.GetTileLayerByName("water")
+			l.SetStatic(true)
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Fatal(err)
+			}
+			if got := l.TileAt(0, 0).Frame(); got != 0 {
+				t.Errorf("Frame() = %v, want 0", got)
+			}
+
+			m.Update(350 * time.Millisecond)
+			if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+				t.Fatal(err)
+			}
+			if got := l.TileAt(0, 0).Frame(); got != 2 {
+				t.Errorf("Frame() = %v, want 2 after updating the clock", got)
+			}
+
+			if err := l.DrawAt(target, 150*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			if got := l.TileAt(1, 1).Frame(); got != 1 {
+				t.Errorf("Frame() = %v, want 1 when drawn at an explicit time", got)
+			}
+		})
+	}
+}
+
+func TestTileLayer_MultipleTilesets(t *testing.T) {
+	for _, path := range []string{"testdata/multi_tileset.tmx", "testdata/multi_tileset.tmj"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := tilepix.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := m.GetTileLayerByName("mixed")
+			if l.Tileset != nil || l.Empty {
+				t.Errorf("Expected layer without a single tileset, got %v", l.Tileset)
+			}
+			tilesets := l.Tilesets()
+			if len(tilesets) != 2 || tilesets[0] != m.Tilesets[0] || tilesets[1] != m.Tilesets[1] {
+				t.Fatalf("Unexpected layer tilesets %v", tilesets)
+			}
+			if _, err := l.Batch(); err == nil {
+				t.Error("Expected error getting single batch of multiple tileset layer")
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer target.Destroy()
+
+			if err := l.Draw(target); err != nil {
+				t.Fatalf("Could not draw layer: %v", err)
+			}
+			for _, ts := range tilesets {
+				if batch, err := l.TilesetBatch(ts); err != nil || batch == nil {
+					t.Errorf("Expected batch for tileset %s, got %v", ts.Name, err)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package tilepix
 
 import (
+	"image/color"
 	"testing"
+	"time"
+
+	"github.com/gopxl/pixel"
 )
 
 func TestTileLayer_String(t *testing.T) {
@@ -30,3 +34,126 @@ func TestTileLayer_String(t *testing.T) {
 		})
 	}
 }
+
+func TestTileLayer_updateFrames(t *testing.T) {
+	m, err := ReadFile("testdata/animated.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := m.GetTileLayerByName("water")
+	if len(l.animatedTiles) != 2 {
+		t.Fatalf("Expected 2 animated tiles, got %d", len(l.animatedTiles))
+	}
+
+	if l.updateFrames(50 * time.Millisecond) {
+		t.Error("Expected no frame change within the first frame")
+	}
+	if !l.updateFrames(150 * time.Millisecond) {
+		t.Error("Expected frame change at the second frame")
+	}
+	if l.updateFrames(250 * time.Millisecond) {
+		t.Error("Expected no frame change within the second frame")
+	}
+	if got := l.TileAt(0, 0).Frame(); got != 1 {
+		t.Errorf("Frame() = %v, want 1", got)
+	}
+	if got := l.TileAt(1, 0).Frame(); got != 4 {
+		t.Errorf("Frame() = %v, want 4 for tile without animation", got)
+	}
+}
+
+func TestTileLayer_tileDrawOrder(t *testing.T) {
+	tests := []struct {
+		path        string
+		renderOrder string
+		want        []int
+	}{
+		{path: "testdata/csv.tmx", renderOrder: "right-down"},
+		{path: "testdata/renderorder.tmx", renderOrder: "left-up", want: []int{5, 4, 3, 2, 1, 0}},
+		{path: "testdata/renderorder.tmj", renderOrder: "left-up", want: []int{5, 4, 3, 2, 1, 0}},
+		// Even columns are shifted down, so are drawn after the odd column in each row.
+		{path: "testdata/staggered.tmx", renderOrder: "right-down", want: []int{1, 0, 2, 4, 3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			m, err := ReadFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.RenderOrder != tt.renderOrder {
+				t.Errorf("RenderOrder = %s, want %s", m.RenderOrder, tt.renderOrder)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			got := m.TileLayers[0].tileDrawOrder()
+			if len(got) != len(tt.want) {
+				t.Fatalf("tileDrawOrder() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("tileDrawOrder() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTileLayer_DrawAtColorMask(t *testing.T) {
+	m, err := ReadFile("testdata/groups.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := m.GetTileLayerByName("ground")
+	tris := &pixel.TrianglesData{}
+	target := pixel.NewBatch(tris, l.Tileset.setSprite())
+
+	// The opacity and tint of the group containing the layer are included.
+	if err := l.DrawAt(target, 0); err != nil {
+		t.Fatal(err)
+	}
+	if tris.Len() == 0 {
+		t.Fatal("No triangles were drawn to the target")
+	}
+	if want := (pixel.RGBA{R: 0.25, A: 0.25}); l.mask != want {
+		t.Errorf("mask = %v, want %v", l.mask, want)
+	}
+
+	// Fading the group is drawn, even though the layer is static.
+	world := m.GetGroupLayerByName("world")
+	world.SetOpacity(1)
+	world.SetTintColor(color.White)
+	if world.TintColor != "#ffffffff" {
+		t.Errorf("TintColor = %s, want #ffffffff", world.TintColor)
+	}
+	if err := l.DrawAt(target, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := pixel.Alpha(0.5); l.mask != want {
+		t.Errorf("mask = %v, want %v", l.mask, want)
+	}
+
+	// Hidden layers, and layers within hidden groups, draw nothing to the target.
+	drawn := tris.Len()
+	l.SetVisible(false)
+	if err := l.DrawAt(target, 0); err != nil {
+		t.Fatal(err)
+	}
+	if tris.Len() != drawn {
+		t.Errorf("Hidden layer drew %d triangles to the target", tris.Len()-drawn)
+	}
+
+	l.SetVisible(true)
+	world.SetVisible(false)
+	if err := l.DrawAt(target, 0); err != nil {
+		t.Fatal(err)
+	}
+	if tris.Len() != drawn {
+		t.Errorf("Layer within hidden group drew %d triangles to the target", tris.Len()-drawn)
+	}
+}
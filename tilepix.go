@@ -10,12 +10,14 @@ package tilepix
 */
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -24,9 +26,19 @@ const (
 	gidHorizontalFlip = 0x80000000
 	gidVerticalFlip   = 0x40000000
 	gidDiagonalFlip   = 0x20000000
-	gidFlip           = gidHorizontalFlip | gidVerticalFlip | gidDiagonalFlip
+	gidRotateHex120   = 0x10000000
+	gidFlip           = gidHorizontalFlip | gidVerticalFlip | gidDiagonalFlip | gidRotateHex120
 )
 
+// Option configures how a map is read; options may be passed to Read, ReadFile, ReadJSON and ReadFileJSON.
+type Option func(*readOptions)
+
+// readOptions holds the configuration set by the options a map is read with.
+type readOptions struct {
+	// extrusion is the number of pixels the edges of tiles are extruded by, see WithTileExtrusion.
+	extrusion int
+}
+
 // ObjectType is used to represent the types an object can be.
 type ObjectType int
 
@@ -44,6 +56,8 @@ func (o ObjectType) String() string {
 		return "Point"
 	case TileObj:
 		return "Tile"
+	case TextObj:
+		return "Text"
 	}
 
 	return "Unknown"
@@ -57,6 +71,7 @@ const (
 	RectangleObj
 	PointObj
 	TileObj
+	TextObj
 )
 
 // Errors which are returned from various places in the package.
@@ -67,7 +82,17 @@ var (
 	ErrInvalidGID            = errors.New("tmx: invalid GID")
 	ErrInvalidObjectType     = errors.New("tmx: the object type requested does not match this object")
 	ErrInvalidPointsField    = errors.New("tmx: invalid points string")
-	ErrInfiniteMap           = errors.New("tmx: infinite maps are not currently supported")
+	ErrInvalidColor          = errors.New("tmx: invalid colour")
+	ErrInvalidUnmarshal      = errors.New("tmx: properties can only be unmarshalled into a non-nil pointer to a struct")
+	ErrMissingProperty       = errors.New("tmx: missing property")
+	ErrInvalidProperty       = errors.New("tmx: invalid property value")
+	ErrUnsupportedField      = errors.New("tmx: unsupported field type for properties")
+	ErrInvalidTemplate       = errors.New("tmx: invalid object template")
+	ErrInvalidPicture        = errors.New("tmx: tileset picture could not be loaded")
+	// ErrInfiniteMap was returned by Read for infinite maps.
+	//
+	// Deprecated: infinite maps are now supported, this error is no longer returned.
+	ErrInfiniteMap = errors.New("tmx: infinite maps are not currently supported")
 )
 
 var (
@@ -92,56 +117,139 @@ func osOpen(name string) (http.File, error) {
 }
 
 // Read will read, decode and initialise a Tiled Map from a data reader.
-// openFileFunc is used to retrieve tilesets and can be nil, in which case os.Open is used.
-func Read(r io.Reader, dir string, openFileFunc func(name string) (http.File, error)) (*Map, error) {
+// openFileFunc is used to retrieve tilesets and object templates and can be nil, in which case os.Open is used.
+// opts configure how the map is read, see Option.
+func Read(r io.Reader, dir string, openFileFunc func(name string) (http.File, error), opts ...Option) (*Map, error) {
 	log.Debug("Read: reading from io.Reader")
-	if openFileFunc == nil {
-		openFileFunc = osOpen
-	}
+
 	var m Map
 	if err := xml.NewDecoder(r).Decode(&m); err != nil {
 		log.WithError(err).Error("Read: could not decode to Map")
 		return nil, err
 	}
 
-	m.dir = dir
+	if err := initMap(&m, dir, openFileFunc, opts); err != nil {
+		log.WithError(err).Error("Read: could not initialise Map")
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ReadFile will read, decode and initialise a Tiled Map from a file path.  Files with a `.tmj` or `.json` extension are
+// read as Tiled JSON maps, all others as TMX.
+func ReadFile(filePath string, opts ...Option) (*Map, error) {
+	log.WithField("Filepath", filePath).Debug("ReadFile: reading file")
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.WithError(err).Error("ReadFile: could not open file")
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(filePath)
+
+	if isJSONFile(filePath) {
+		return ReadJSON(f, dir, nil, opts...)
+	}
+
+	return Read(f, dir, nil, opts...)
+}
+
+// ReadFileJSON will read, decode and initialise a Tiled JSON Map from a file path.
+func ReadFileJSON(filePath string, opts ...Option) (*Map, error) {
+	log.WithField("Filepath", filePath).Debug("ReadFileJSON: reading file")
 
-	if m.Infinite {
-		log.WithError(ErrInfiniteMap).Error("Read: map has attribute 'infinite=true', not supported")
-		return nil, ErrInfiniteMap
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.WithError(err).Error("ReadFileJSON: could not open file")
+		return nil, err
 	}
+	defer f.Close()
+
+	return ReadJSON(f, filepath.Dir(filePath), nil, opts...)
+}
+
+// ReadJSON will read, decode and initialise a Tiled JSON Map from a data reader.  The Map produced is the same as if
+// the equivalent TMX file had been read with Read.
+// openFileFunc is used to retrieve tilesets and object templates and can be nil, in which case os.Open is used.
+// opts configure how the map is read, see Option.
+func ReadJSON(r io.Reader, dir string, openFileFunc func(name string) (http.File, error), opts ...Option) (*Map, error) {
+	log.Debug("ReadJSON: reading from io.Reader")
 
-	log.WithField("Tileset count", len(m.Tilesets)).Debug("Read: checking for tileset sources")
+	var jm jsonMap
+	if err := json.NewDecoder(r).Decode(&jm); err != nil {
+		log.WithError(err).Error("ReadJSON: could not decode JSON")
+		return nil, err
+	}
+
+	m, err := jm.toMap()
+	if err != nil {
+		log.WithError(err).Error("ReadJSON: could not convert to Map")
+		return nil, err
+	}
+
+	if err := initMap(m, dir, openFileFunc, opts); err != nil {
+		log.WithError(err).Error("ReadJSON: could not initialise Map")
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// initMap will load external tilesets, decode layers and initialise everything which is required before a decoded Map
+// can be used, applying the options provided.
+func initMap(m *Map, dir string, openFileFunc func(name string) (http.File, error), opts []Option) error {
+	if openFileFunc == nil {
+		openFileFunc = osOpen
+	}
+
+	m.dir = dir
+	m.openFileFunc = openFileFunc
+
+	log.WithField("Tileset count", len(m.Tilesets)).Debug("initMap: checking for tileset sources")
 	for i, ts := range m.Tilesets {
 		if ts.Source != "" {
-			f, err := openFileFunc(filepath.Join(dir, ts.Source))
+			sourcePath := filepath.Join(dir, ts.Source)
+			f, err := openFileFunc(sourcePath)
 			if err != nil {
-				log.WithError(err).Error("Read: could not read tileset source")
-				return nil, err
+				log.WithError(err).Error("initMap: could not read tileset source")
+				return err
 			}
-			sourceTs, err := readTileset(f, dir)
+			// External tilesets may be TSX or JSON, regardless of the format of the map.  Assets are relative to the
+			// tileset file.
+			sourceTs, err := readTilesetSource(f, sourcePath, filepath.Dir(sourcePath))
 			_ = f.Close()
 			if err != nil {
-				log.WithError(err).Error("Read: could not read tileset source")
-				return nil, err
+				log.WithError(err).Error("initMap: could not read tileset source")
+				return err
 			}
 			sourceTs.FirstGID = ts.FirstGID
+			sourceTs.Source = ts.Source
 			m.Tilesets[i] = sourceTs
 		}
 	}
 
+	if err := m.resolveTemplates(); err != nil {
+		log.WithError(err).Error("initMap: could not resolve object templates")
+		return err
+	}
+
 	if err := m.decodeLayers(); err != nil {
-		log.WithError(err).Error("Read: could not decode layers")
-		return nil, err
+		log.WithError(err).Error("initMap: could not decode layers")
+		return err
 	}
 
 	m.setParents()
 
-	log.WithField("TileLayer count", len(m.TileLayers)).Debug("Read: processing layer tilesets")
-	for _, l := range m.TileLayers {
+	tileLayers := m.allTileLayers()
+	log.WithField("TileLayer count", len(tileLayers)).Debug("initMap: processing layer tilesets")
+	for _, l := range tileLayers {
+		l.tilesets = getTilesets(l, m.Tilesets)
 		tileset, isEmpty, usesMultipleTilesets := getTileset(l)
 		if usesMultipleTilesets {
-			log.Debug("Read: multiple tilesets in use")
+			log.WithField("Layer", l.Name).Debug("initMap: multiple tilesets in use, drawing with a batch per tileset")
 			continue
 		}
 		l.Empty, l.Tileset = isEmpty, tileset
@@ -149,31 +257,36 @@ func Read(r io.Reader, dir string, openFileFunc func(name string) (http.File, er
 
 	// Tiled calculates co-ordinates from the top-left, flipping the y co-ordinate means we match the standard
 	// bottom-left calculation.
-	log.WithField("Object layer count", len(m.ObjectGroups)).Debug("Read: processing object layers")
-	for _, og := range m.ObjectGroups {
+	objectGroups := m.allObjectGroups()
+	log.WithField("Object layer count", len(objectGroups)).Debug("initMap: processing object layers")
+	for _, og := range objectGroups {
 		og.flipY()
 	}
 
-	log.WithField("Tileset count", len(m.Tilesets)).Debug("Read: processing tilesets")
+	log.WithField("Tileset count", len(m.Tilesets)).Debug("initMap: processing tilesets")
 	for _, ts := range m.Tilesets {
 		ts.setSprite()
 	}
 
-	return &m, nil
-}
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.extrusion > 0 {
+		if err := m.extrudeTiles(o.extrusion); err != nil {
+			log.WithError(err).Error("initMap: could not extrude tiles")
+			return err
+		}
+	}
 
-// ReadFile will read, decode and initialise a Tiled Map from a file path.
-func ReadFile(filePath string) (*Map, error) {
-	log.WithField("Filepath", filePath).Debug("ReadFile: reading file")
+	return nil
+}
 
-	f, err := os.Open(filePath)
-	if err != nil {
-		log.WithError(err).Error("ReadFile: could not open file")
-		return nil, err
+func isJSONFile(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".tmj", ".tsj", ".tj", ".json":
+		return true
 	}
-	defer f.Close()
-
-	dir := filepath.Dir(filePath)
 
-	return Read(f, dir, nil)
+	return false
 }
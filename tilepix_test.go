@@ -41,6 +41,12 @@ func TestReadFile(t *testing.T) {
 			want:     nil,
 			wantErr:  false,
 		},
+		{
+			name:     "base64-zstd",
+			filepath: "testdata/base64-zstd.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
 		{
 			name:     "csv",
 			filepath: "testdata/csv.tmx",
@@ -63,7 +69,31 @@ func TestReadFile(t *testing.T) {
 			name:     "map is infinite",
 			filepath: "testdata/infinite.tmx",
 			want:     nil,
-			wantErr:  true,
+			wantErr:  false,
+		},
+		{
+			name:     "infinite csv",
+			filepath: "testdata/infinite-csv.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "infinite base64",
+			filepath: "testdata/infinite-base64.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "infinite base64-gzip",
+			filepath: "testdata/infinite-gzip.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "infinite base64-zlib",
+			filepath: "testdata/infinite-zlib.tmx",
+			want:     nil,
+			wantErr:  false,
 		},
 		{
 			name:     "external tileset",
@@ -71,6 +101,18 @@ func TestReadFile(t *testing.T) {
 			want:     nil,
 			wantErr:  false,
 		},
+		{
+			name:     "external JSON tileset",
+			filepath: "testdata/external_tileset_json.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "external tileset sniffed",
+			filepath: "testdata/external_tileset_sniffed.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,11 +1,16 @@
 package tilepix
 
 import (
+	"bufio"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"image"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/gopxl/pixel"
 	log "github.com/sirupsen/logrus"
@@ -18,23 +23,45 @@ import (
    |_| |_|_\___/__/\___|\__|
 */
 
-// Tileset is a TMX file structure which represents a Tiled Tileset
+// Tileset is a TMX file structure which represents a Tiled Tileset.  Tilesets are either a single image holding all
+// tiles, or a collection of images where each Tile has its own Image.
 type Tileset struct {
-	FirstGID   GID         `xml:"firstgid,attr"`
-	Source     string      `xml:"source,attr"`
-	Name       string      `xml:"name,attr"`
-	TileWidth  int         `xml:"tilewidth,attr"`
-	TileHeight int         `xml:"tileheight,attr"`
-	Spacing    int         `xml:"spacing,attr"`
-	Margin     int         `xml:"margin,attr"`
-	Properties []*Property `xml:"properties>property"`
-	Image      *Image      `xml:"image"`
-	Tiles      []*Tile     `xml:"tile"`
-	Tilecount  int         `xml:"tilecount,attr"`
-	Columns    int         `xml:"columns,attr"`
+	FirstGID   GID        `xml:"firstgid,attr"`
+	Source     string     `xml:"source,attr"`
+	Name       string     `xml:"name,attr"`
+	TileWidth  int        `xml:"tilewidth,attr"`
+	TileHeight int        `xml:"tileheight,attr"`
+	Spacing    int        `xml:"spacing,attr"`
+	Margin     int        `xml:"margin,attr"`
+	Properties Properties `xml:"properties>property"`
+	Image      *Image     `xml:"image"`
+	Tiles      []*Tile    `xml:"tile"`
+	Tilecount  int        `xml:"tilecount,attr"`
+	Columns    int        `xml:"columns,attr"`
+	// TileOffset is the offset, in pixels, applied when drawing tiles of this tileset.
+	TileOffset *TileOffset `xml:"tileoffset"`
+	// Grid is the orientation and size of the grid used by Tiled when editing tiles of this tileset.
+	Grid *Grid `xml:"grid"`
+	// ObjectAlignment is the point of tile objects which their position refers to; one of "unspecified", "topleft",
+	// "top", "topright", "left", "center", "right", "bottomleft", "bottom" or "bottomright".  Unspecified is
+	// "bottomleft" on orthogonal maps and "bottom" on isometric maps.
+	ObjectAlignment string `xml:"objectalignment,attr"`
 
 	sprite  *pixel.Sprite
 	picture pixel.Picture
+	// frames holds the area of each tile within picture for image collection tilesets, where the images of all tiles
+	// are packed into a single picture so that they may be drawn with one batch.
+	frames map[ID]pixel.Rect
+	// atlasOrigin is the bottom-left of the tilesets' own image within picture, when the tilesets of the map have been
+	// packed into a single picture with Map.PackTilesets.
+	atlasOrigin pixel.Vec
+	// ownPicture is the picture of the tileset itself, which is packed by Map.PackTilesets.
+	ownPicture pixel.Picture
+	// imageHeight is the height in pixels of the loaded tileset image, which the frames of tiles are measured against.
+	// The size given in the TSX file is not used as Tiled may omit it.
+	imageHeight int
+	// tilesByID indexes Tiles, it is built when first needed.
+	tilesByID map[ID]*Tile
 
 	// parentMap is the map which contains this object
 	parentMap *Map
@@ -70,7 +97,41 @@ func readTilesetFile(filePath string) (*Tileset, error) {
 
 	dir := filepath.Dir(filePath)
 
-	return readTileset(f, dir)
+	return readTilesetSource(f, filePath, dir)
+}
+
+func readTilesetJSON(r io.Reader, dir string) (*Tileset, error) {
+	log.Debug("readTilesetJSON: reading from io.Reader")
+
+	var jt jsonTileset
+	if err := json.NewDecoder(r).Decode(&jt); err != nil {
+		log.WithError(err).Error("readTilesetJSON: could not decode to Tileset")
+		return nil, err
+	}
+
+	t := jt.toTileset()
+	t.dir = dir
+
+	return validate(*t)
+}
+
+// readTilesetSource will read an external tileset in either the TSX or JSON format.  The format is chosen from the
+// extension of name, and when that is not recognised by sniffing the content.
+func readTilesetSource(r io.Reader, name, dir string) (*Tileset, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tsx", ".xml":
+		return readTileset(r, dir)
+	case ".tsj", ".json":
+		return readTilesetJSON(r, dir)
+	}
+
+	br := bufio.NewReader(r)
+	if isJSONContent(br) {
+		log.WithField("Name", name).Debug("readTilesetSource: content sniffed as JSON")
+		return readTilesetJSON(br, dir)
+	}
+
+	return readTileset(br, dir)
 }
 
 // GenerateTileObjectLayer will create a new ObjectGroup for the mapping of Objects to individual tiles.
@@ -111,12 +172,30 @@ func (ts Tileset) GenerateTileObjectLayer(tileLayers []*TileLayer) ObjectGroup {
 }
 
 func validate(t Tileset) (*Tileset, error) {
-	if t.Columns < 1 {
+	// Image collection tilesets have no columns.
+	if t.Image != nil && t.Columns < 1 {
 		return nil, fmt.Errorf("Tileset columns value not valid")
 	}
 	return &t, nil
 }
 
+// GetTile returns the tile with the ID provided, or nil if the tileset does not define any data for that tile.
+func (ts *Tileset) GetTile(id ID) *Tile {
+	if ts.tilesByID == nil {
+		ts.tilesByID = make(map[ID]*Tile, len(ts.Tiles))
+		for _, t := range ts.Tiles {
+			ts.tilesByID[t.ID] = t
+		}
+	}
+
+	return ts.tilesByID[id]
+}
+
+// Props returns the custom properties of the tileset.
+func (ts *Tileset) Props() Properties {
+	return ts.Properties
+}
+
 func (ts *Tileset) String() string {
 	return fmt.Sprintf(
 		"TileSet{Name: %s, Tile size: %dx%d, Tile spacing: %d, Tilecount: %d, Properties: %v}",
@@ -129,6 +208,12 @@ func (ts *Tileset) String() string {
 	)
 }
 
+// UnmarshalProperties fills the struct pointed to by v from the custom properties of the tileset.  See
+// Properties.Unmarshal for details of the struct tags used.
+func (ts *Tileset) UnmarshalProperties(v interface{}) error {
+	return ts.Properties.Unmarshal(v)
+}
+
 func (ts *Tileset) setParent(m *Map) {
 	ts.parentMap = m
 
@@ -155,6 +240,10 @@ func (ts *Tileset) setSprite() pixel.Picture {
 		dir = ts.parentMap.dir
 	}
 
+	if ts.Image == nil {
+		return ts.setCollectionSprite(dir)
+	}
+
 	sprite, pictureData, err := loadSpriteFromFile(filepath.Join(dir, ts.Image.Source))
 	if err != nil {
 		log.WithField("Filepath", filepath.Join(dir, ts.Image.Source)).WithError(err).Error("Tileset.setSprite: could not load sprite from file")
@@ -163,9 +252,159 @@ func (ts *Tileset) setSprite() pixel.Picture {
 
 	ts.sprite = sprite
 	ts.picture = pictureData
+	ts.ownPicture = pictureData
+	ts.imageHeight = int(pictureData.Bounds().H())
 	return ts.picture
 }
 
+// setCollectionSprite loads the images of all tiles of an image collection tileset, and packs them into a single
+// picture.  The dir is the directory the tile images are relative to.
+func (ts *Tileset) setCollectionSprite(dir string) pixel.Picture {
+	var (
+		ids    []ID
+		images []image.Image
+	)
+	for _, t := range ts.Tiles {
+		if t.Image == nil {
+			continue
+		}
+
+		img, err := loadImageFile(filepath.Join(dir, t.Image.Source))
+		if err != nil {
+			log.WithField("Tile", t).WithError(err).Error("Tileset.setCollectionSprite: could not load tile image")
+			return nil
+		}
+		ids = append(ids, t.ID)
+		images = append(images, img)
+	}
+
+	packed, rects := packImages(images, 0)
+
+	ts.frames = make(map[ID]pixel.Rect, len(ids))
+	for i, id := range ids {
+		ts.frames[id] = pictureRect(rects[i], packed.Bounds().Dy())
+	}
+
+	ts.picture = pixel.PictureDataFromImage(packed)
+	ts.ownPicture = ts.picture
+	ts.sprite = pixel.NewSprite(ts.picture, ts.picture.Bounds())
+	return ts.picture
+}
+
+// alignment returns the point of tile objects which their position refers to, as a fraction of the size of the object
+// measured from its bottom-left.  The orientation of the map is used when the alignment is unspecified.
+func (ts *Tileset) alignment(orientation string) pixel.Vec {
+	switch ts.ObjectAlignment {
+	case "topleft":
+		return pixel.V(0, 1)
+	case "top":
+		return pixel.V(0.5, 1)
+	case "topright":
+		return pixel.V(1, 1)
+	case "left":
+		return pixel.V(0, 0.5)
+	case "center":
+		return pixel.V(0.5, 0.5)
+	case "right":
+		return pixel.V(1, 0.5)
+	case "bottomleft":
+		return pixel.V(0, 0)
+	case "bottom":
+		return pixel.V(0.5, 0)
+	case "bottomright":
+		return pixel.V(1, 0)
+	}
+
+	if orientation == "isometric" {
+		return pixel.V(0.5, 0)
+	}
+	return pixel.ZV
+}
+
+// numRows returns the number of rows of tiles in the tileset image, this is zero for image collection tilesets.
+func (ts *Tileset) numRows() int {
+	if ts.Columns < 1 {
+		return 0
+	}
+	return ts.Tilecount / ts.Columns
+}
+
+// extrude rebuilds the picture of the tileset with each tile surrounded by extrusion pixels copied from its edges, and
+// sets the frames of the tiles within the new picture.
+func (ts *Tileset) extrude(pic *pixel.PictureData, extrusion int) {
+	var ids []ID
+	if ts.frames != nil {
+		for id := range ts.frames {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	} else {
+		for id := 0; id < ts.Tilecount; id++ {
+			ids = append(ids, ID(id))
+		}
+	}
+
+	img := pic.Image()
+	tiles := make([]image.Image, len(ids))
+	for i, id := range ids {
+		r := imageRect(ts.tileFrame(id).Moved(pic.Bounds().Min.Scaled(-1)), img.Bounds().Dy())
+		tiles[i] = img.SubImage(r.Add(img.Bounds().Min))
+	}
+
+	packed, rects := packImages(tiles, extrusion)
+	ts.frames = make(map[ID]pixel.Rect, len(ids))
+	for i, id := range ids {
+		extrudeEdges(packed, rects[i], extrusion)
+		ts.frames[id] = pictureRect(rects[i], packed.Bounds().Dy())
+	}
+
+	ts.picture = pixel.PictureDataFromImage(packed)
+	ts.ownPicture = ts.picture
+	ts.sprite = pixel.NewSprite(ts.picture, ts.picture.Bounds())
+	ts.atlasOrigin = pixel.ZV
+}
+
+// imageTileRect returns the area of the tileset image which holds the tile, from the top-left of the image.  Tiles are
+// laid out in rows of Columns tiles, with Margin pixels around the image and Spacing pixels between tiles.
+func (ts *Tileset) imageTileRect(id ID) image.Rectangle {
+	x, y := int(id)%ts.Columns, int(id)/ts.Columns
+	min := image.Pt(ts.Margin+x*(ts.TileWidth+ts.Spacing), ts.Margin+y*(ts.TileHeight+ts.Spacing))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(ts.TileWidth, ts.TileHeight))}
+}
+
+// tileFrame returns the area of the tileset picture which holds the tile.  The picture must have been loaded with
+// setSprite.
+func (ts *Tileset) tileFrame(id ID) pixel.Rect {
+	// Image collection tilesets, and tilesets which have been extruded, have the frame of each tile set.
+	if ts.frames != nil {
+		return ts.frames[id].Moved(ts.atlasOrigin)
+	}
+
+	return pictureRect(ts.imageTileRect(id), ts.imageHeight).Moved(ts.atlasOrigin)
+}
+
+// tileOffset returns the offset applied when drawing tiles of the tileset, in game co-ordinates.
+func (ts *Tileset) tileOffset() pixel.Vec {
+	if ts.TileOffset == nil {
+		return pixel.ZV
+	}
+	return ts.TileOffset.V()
+}
+
+// tileSize returns the size of the tile in pixels.  Tiles of image collection tilesets are the size of their image.
+func (ts *Tileset) tileSize(id ID) pixel.Vec {
+	if ts.Image == nil {
+		if frame, ok := ts.frames[id]; ok {
+			return frame.Size()
+		}
+		if t := ts.GetTile(id); t != nil && t.Image != nil {
+			return pixel.V(float64(t.Image.Width), float64(t.Image.Height))
+		}
+	}
+
+	return pixel.V(float64(ts.TileWidth), float64(ts.TileHeight))
+}
+
 // TileObjects will return all ObjectGroups contained in Tiles.
 func (ts Tileset) TileObjects() map[ID]*ObjectGroup {
 	objs := make(map[ID]*ObjectGroup)
@@ -178,6 +417,54 @@ func (ts Tileset) TileObjects() map[ID]*ObjectGroup {
 	return objs
 }
 
+// TileOffset is a TMX file structure holding the offset applied when drawing the tiles of a tileset.  As with all TMX
+// co-ordinates, a positive Y moves tiles down.
+type TileOffset struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
+
+func (to *TileOffset) String() string {
+	return fmt.Sprintf("TileOffset{%d, %d}", to.X, to.Y)
+}
+
+// V returns the offset in game co-ordinates, where a positive Y moves tiles up.
+func (to *TileOffset) V() pixel.Vec {
+	return pixel.V(float64(to.X), -float64(to.Y))
+}
+
+// Grid is a TMX file structure holding the grid Tiled uses when editing the tiles of a tileset, such as for terrain
+// overlays and tile collision shapes.
+type Grid struct {
+	// Orientation is either "orthogonal" or "isometric".
+	Orientation string `xml:"orientation,attr"`
+	Width       int    `xml:"width,attr"`
+	Height      int    `xml:"height,attr"`
+}
+
+func (g *Grid) String() string {
+	return fmt.Sprintf("Grid{%s, %dx%d}", g.Orientation, g.Width, g.Height)
+}
+
+// getTilesets returns those of the tilesets provided which are used by the layer, in the order provided.
+func getTilesets(l *TileLayer, tilesets []*Tileset) []*Tileset {
+	used := make(map[*Tileset]bool)
+	for _, tile := range l.DecodedTiles {
+		if !tile.Nil {
+			used[tile.Tileset] = true
+		}
+	}
+
+	var layerTilesets []*Tileset
+	for _, ts := range tilesets {
+		if used[ts] {
+			layerTilesets = append(layerTilesets, ts)
+		}
+	}
+
+	return layerTilesets
+}
+
 func getTileset(l *TileLayer) (tileset *Tileset, isEmpty, usesMultipleTilesets bool) {
 	for _, tile := range l.DecodedTiles {
 		if !tile.Nil {
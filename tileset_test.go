@@ -1,7 +1,10 @@
 package tilepix
 
 import (
+	"image/color"
 	"testing"
+
+	"github.com/gopxl/pixel"
 )
 
 func TestTileset_String(t *testing.T) {
@@ -44,3 +47,117 @@ func TestTileset_String(t *testing.T) {
 		})
 	}
 }
+
+func TestReadTilesetFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		filepath string
+	}{
+		{
+			name:     "TSX",
+			filepath: "testdata/tileset.tsx",
+		},
+		{
+			name:     "JSON",
+			filepath: "testdata/tileset.tsj",
+		},
+		{
+			name:     "JSON without extension",
+			filepath: "testdata/tileset_sniffed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := readTilesetFile(tt.filepath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ts.Name != "tileset" || ts.TileWidth != 16 || ts.TileHeight != 16 || ts.Tilecount != 15 || ts.Columns != 3 {
+				t.Errorf("Unexpected tileset %v", ts)
+			}
+			if ts.Image == nil || ts.Image.Source != "tileset.png" || ts.Image.Width != 48 || ts.Image.Height != 80 {
+				t.Errorf("Unexpected tileset image %v", ts.Image)
+			}
+			if ts.dir != "testdata" {
+				t.Errorf("Tileset dir = %s, want testdata", ts.dir)
+			}
+		})
+	}
+}
+
+func TestTileset_collection(t *testing.T) {
+	m, err := ReadFile("testdata/collection.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := m.Tilesets[0]
+	if got := ts.tileSize(0); got != pixel.V(32, 48) {
+		t.Errorf("tileSize(0) = %v, want 32x48", got)
+	}
+	if got := ts.tileSize(3); got != pixel.V(16, 16) {
+		t.Errorf("tileSize(3) = %v, want 16x16", got)
+	}
+
+	pic, ok := ts.setSprite().(*pixel.PictureData)
+	if !ok {
+		t.Fatalf("Expected picture data, got %T", ts.setSprite())
+	}
+	if got, want := pic.Color(ts.tileFrame(0).Center()), pixel.RGB(0, 1, 0); got != want {
+		t.Errorf("Tree colour = %v, want %v", got, want)
+	}
+	if got, want := pic.Color(ts.tileFrame(3).Center()), pixel.ToRGBA(color.NRGBA{R: 128, G: 128, B: 128, A: 255}); got != want {
+		t.Errorf("Rock colour = %v, want %v", got, want)
+	}
+}
+
+func TestTileset_alignment(t *testing.T) {
+	tests := []struct {
+		alignment   string
+		orientation string
+		want        pixel.Vec
+	}{
+		{alignment: "", orientation: "orthogonal", want: pixel.V(0, 0)},
+		{alignment: "unspecified", orientation: "isometric", want: pixel.V(0.5, 0)},
+		{alignment: "topleft", orientation: "isometric", want: pixel.V(0, 1)},
+		{alignment: "center", orientation: "orthogonal", want: pixel.V(0.5, 0.5)},
+		{alignment: "bottomright", orientation: "orthogonal", want: pixel.V(1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alignment+" "+tt.orientation, func(t *testing.T) {
+			ts := &Tileset{ObjectAlignment: tt.alignment}
+			if got := ts.alignment(tt.orientation); got != tt.want {
+				t.Errorf("alignment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTileset_tileFrame(t *testing.T) {
+	// Tiled may omit the size of the tileset image, the frames are measured against the loaded picture.
+	for _, path := range []string{"testdata/spacing.tmx", "testdata/spacing_unsized.tmx"} {
+		t.Run(path, func(t *testing.T) {
+			m, err := ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts := m.Tilesets[0]
+			pic := ts.setSprite().(*pixel.PictureData)
+			for id, want := range []pixel.RGBA{pixel.RGB(1, 0, 0), pixel.RGB(0, 1, 0), pixel.RGB(0, 0, 1), pixel.RGB(1, 1, 0)} {
+				frame := ts.tileFrame(ID(id))
+				if frame.Size() != pixel.V(4, 4) {
+					t.Errorf("Tile %d frame %v, want size 4x4", id, frame)
+				}
+				// Every corner of the frame must be within the tile, not the margin or spacing.
+				for _, v := range frame.Vertices() {
+					at := frame.Center().Add(v.Sub(frame.Center()).Scaled(0.8))
+					if got := pic.Color(at); got != want {
+						t.Errorf("Tile %d colour at %v = %v, want %v", id, at, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package tilepix
+
+import (
+	"fmt"
+	"image/color"
+	"reflect"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _   _                              _           _
+ | | | | _ _   _ __   __ _  _ _  ___| |_   __ _ | |
+ | |_| || ' \ | '  \ / _` || '_|(_-<| ' \ / _` || |
+  \___/ |_||_||_|_|_|\__,_||_|  /__/|_||_|\__,_||_|
+*/
+
+var (
+	colorType  = reflect.TypeOf(color.NRGBA{})
+	objectType = reflect.TypeOf((*Object)(nil))
+)
+
+// propertyTag holds the parsed `tiled` struct tag of a field.
+type propertyTag struct {
+	name       string
+	def        string
+	hasDefault bool
+	optional   bool
+}
+
+// Unmarshal fills the struct pointed to by v from the properties.  Fields are matched to properties by the `tiled`
+// struct tag, which holds the property name followed by any options:
+//
+//	type Enemy struct {
+//		Name   string      `tiled:"name"`
+//		Speed  float64     `tiled:"speed,default=1.5"`
+//		Tint   color.NRGBA `tiled:"tint,optional"`
+//		Target *Object     `tiled:"target,optional"`
+//		Stats  Stats       `tiled:"stats"`
+//		Notes  string      `tiled:"-"`
+//	}
+//
+// Fields without a tag use the field name, and fields tagged "-" are ignored.  A missing property is an error unless
+// the field is optional or has a default; the default must be the last option, and may contain commas.  Struct fields
+// are filled from the members of class properties, and *Object fields from object properties.  Strings, bools, ints,
+// uints, floats and color.NRGBA fields are supported, as are structs of these.
+func (ps Properties) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		err := fmt.Errorf("%w, got %T", ErrInvalidUnmarshal, v)
+		log.WithError(err).Error("Properties.Unmarshal: invalid target")
+		return err
+	}
+
+	if err := ps.unmarshalStruct(rv.Elem(), ""); err != nil {
+		log.WithError(err).Error("Properties.Unmarshal: could not unmarshal properties")
+		return err
+	}
+
+	return nil
+}
+
+// unmarshalStruct fills the struct value from the properties.  The path is the dotted path of the class containing the
+// properties, and is used in errors.
+func (ps Properties) unmarshalStruct(sv reflect.Value, path string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := parsePropertyTag(field)
+		if tag.name == "-" {
+			continue
+		}
+
+		if err := unmarshalField(sv.Field(i), ps.Get(tag.name), tag, path+tag.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parsePropertyTag returns the parsed `tiled` struct tag of the field.
+func parsePropertyTag(field reflect.StructField) propertyTag {
+	opts := strings.Split(field.Tag.Get("tiled"), ",")
+
+	tag := propertyTag{name: opts[0]}
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+
+	for i, opt := range opts[1:] {
+		switch {
+		case opt == "optional":
+			tag.optional = true
+		case strings.HasPrefix(opt, "default="):
+			tag.def = strings.TrimPrefix(strings.Join(opts[i+1:], ","), "default=")
+			tag.hasDefault = true
+			return tag
+		}
+	}
+
+	return tag
+}
+
+// unmarshalField sets the field value from the property, which is nil if the property is missing.  The name is the
+// full dotted path of the property, and is used in errors.
+func unmarshalField(fv reflect.Value, p *Property, tag propertyTag, name string) error {
+	ft := fv.Type()
+
+	// Class properties fill struct fields.  A missing class is treated as having no members, as Tiled does not save
+	// classes which are unchanged from their defaults.
+	if ft.Kind() == reflect.Struct && ft != colorType {
+		var members Properties
+		if p != nil {
+			if p.Type != ClassProp {
+				return fmt.Errorf("%w: %q is a %s property, a class is required for %s", ErrInvalidProperty, name, p.Type, ft)
+			}
+			members = p.Properties
+		}
+		return members.unmarshalStruct(fv, name+".")
+	}
+
+	value := ""
+	if p != nil {
+		value = p.Value
+	}
+
+	// Unset colour and object properties are saved by Tiled with empty and zero values respectively.
+	missing := p == nil ||
+		(ft == colorType && value == "") ||
+		(ft == objectType && (value == "" || value == "0"))
+	if missing {
+		switch {
+		case tag.hasDefault && ft != objectType:
+			value = tag.def
+		case tag.optional:
+			return nil
+		default:
+			return fmt.Errorf("%w: %q", ErrMissingProperty, name)
+		}
+	}
+
+	switch {
+	case ft == colorType:
+		c, err := parseColor(value)
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a colour: %q", ErrInvalidProperty, name, value)
+		}
+		fv.Set(reflect.ValueOf(c))
+		return nil
+	case ft == objectType:
+		o := p.Object()
+		if o == nil {
+			return fmt.Errorf("%w: %q refers to object %s, which is not in the map", ErrInvalidProperty, name, value)
+		}
+		fv.Set(reflect.ValueOf(o))
+		return nil
+	}
+
+	switch ft.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a bool: %q", ErrInvalidProperty, name, value)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s: %q", ErrInvalidProperty, name, ft, value)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s: %q", ErrInvalidProperty, name, ft, value)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, ft.Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s: %q", ErrInvalidProperty, name, ft, value)
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("%w: %s for property %q", ErrUnsupportedField, ft, name)
+	}
+
+	return nil
+}
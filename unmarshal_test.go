@@ -0,0 +1,122 @@
+package tilepix
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+)
+
+func TestProperties_Unmarshal(t *testing.T) {
+	type stats struct {
+		HP    int     `tiled:"hp"`
+		Armor float32 `tiled:"armor,default=1.5"`
+	}
+	type enemy struct {
+		Name    string
+		Speed   uint8       `tiled:"speed"`
+		Flying  bool        `tiled:"flying,optional"`
+		Tint    color.NRGBA `tiled:"tint,default=#ff0000"`
+		Stats   stats       `tiled:"stats"`
+		Ignored string      `tiled:"-"`
+		Label   string      `tiled:"label,default=a, b"`
+	}
+
+	tests := []struct {
+		name    string
+		props   Properties
+		want    enemy
+		wantErr error
+	}{
+		{
+			name: "All set",
+			props: Properties{
+				{Name: "Name", Type: StringProp, Value: "Ogre"},
+				{Name: "speed", Type: IntProp, Value: "3"},
+				{Name: "flying", Type: BoolProp, Value: "true"},
+				{Name: "tint", Type: ColorProp, Value: "#8000ff00"},
+				{Name: "stats", Type: ClassProp, Properties: Properties{
+					{Name: "hp", Type: IntProp, Value: "40"},
+					{Name: "armor", Type: FloatProp, Value: "2"},
+				}},
+				{Name: "label", Type: StringProp, Value: "boss"},
+			},
+			want: enemy{
+				Name:   "Ogre",
+				Speed:  3,
+				Flying: true,
+				Tint:   color.NRGBA{G: 0xff, A: 0x80},
+				Stats:  stats{HP: 40, Armor: 2},
+				Label:  "boss",
+			},
+		},
+		{
+			name: "Defaults",
+			props: Properties{
+				{Name: "Name", Type: StringProp, Value: "Imp"},
+				{Name: "speed", Type: IntProp, Value: "1"},
+				{Name: "stats", Type: ClassProp, Properties: Properties{
+					{Name: "hp", Type: IntProp, Value: "5"},
+				}},
+			},
+			want: enemy{
+				Name:  "Imp",
+				Speed: 1,
+				Tint:  color.NRGBA{R: 0xff, A: 0xff},
+				Stats: stats{HP: 5, Armor: 1.5},
+				Label: "a, b",
+			},
+		},
+		{
+			name: "Missing",
+			props: Properties{
+				{Name: "Name", Type: StringProp, Value: "Imp"},
+				{Name: "speed", Type: IntProp, Value: "1"},
+			},
+			wantErr: ErrMissingProperty,
+		},
+		{
+			name: "Ill-typed",
+			props: Properties{
+				{Name: "Name", Type: StringProp, Value: "Imp"},
+				{Name: "speed", Type: IntProp, Value: "300"},
+			},
+			wantErr: ErrInvalidProperty,
+		},
+		{
+			name: "Not a class",
+			props: Properties{
+				{Name: "Name", Type: StringProp, Value: "Imp"},
+				{Name: "speed", Type: IntProp, Value: "1"},
+				{Name: "stats", Type: IntProp, Value: "1"},
+			},
+			wantErr: ErrInvalidProperty,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got enemy
+			err := tt.props.Unmarshal(&got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProperties_UnmarshalInvalid(t *testing.T) {
+	var notStruct int
+	if err := (Properties{}).Unmarshal(&notStruct); !errors.Is(err, ErrInvalidUnmarshal) {
+		t.Errorf("Unmarshal() error = %v, want %v", err, ErrInvalidUnmarshal)
+	}
+
+	var unsupported struct {
+		List []string `tiled:"list"`
+	}
+	props := Properties{{Name: "list", Type: StringProp, Value: "a"}}
+	if err := props.Unmarshal(&unsupported); !errors.Is(err, ErrUnsupportedField) {
+		t.Errorf("Unmarshal() error = %v, want %v", err, ErrUnsupportedField)
+	}
+}
@@ -1,14 +1,31 @@
 package tilepix
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"image"
+	"image/color"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gopxl/pixel"
 	log "github.com/sirupsen/logrus"
 )
 
+// isJSONContent reports whether the buffered content looks like JSON rather than XML, without consuming it.
+func isJSONContent(br *bufio.Reader) bool {
+	const peekSize = 512
+
+	b, _ := br.Peek(peekSize)
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	b = bytes.TrimLeft(b, " \t\r\n")
+
+	return len(b) > 0 && b[0] == '{'
+}
+
 // loadPicture loads picture data from a Reader and will decode based using the built in image
 // package.
 func loadPicture(img io.Reader) (pixel.Picture, error) {
@@ -41,17 +58,71 @@ func loadSpriteFromFile(path string) (*pixel.Sprite, pixel.Picture, error) {
 	return loadSprite(f)
 }
 
-func tileIDToCoord(tID ID, numColumns int, numRows int) (x int, y int) {
-	tIDInt := int(tID)
-	x = tIDInt % numColumns
-	y = numRows - (tIDInt / numColumns) - 1
-	return
-}
-
-func indexToGamePos(idx int, width int, height int) pixel.Vec {
+// tileToGamePos converts tile co-ordinates, which are from the top-left, to the bottom-left tile position in game
+// co-ordinates.  The result is in tiles rather than pixels.
+func tileToGamePos(x, y int, height int) pixel.Vec {
 	gamePos := pixel.V(
-		float64(idx%width),
-		float64(height)-float64(idx/width)-1,
+		float64(x),
+		float64(height)-float64(y)-1,
 	)
 	return gamePos
 }
+
+// formatColor returns the colour provided in the format Tiled uses, #AARRGGBB.  A nil colour is returned as an empty
+// string.
+func formatColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x%02x", n.A, n.R, n.G, n.B)
+}
+
+// parseColor parses a Tiled colour, which is in the format #RRGGBB or #AARRGGBB.  The leading # is optional.
+func parseColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		log.WithError(ErrInvalidColor).WithField("Colour", s).Error("parseColor: unexpected colour length")
+		return color.NRGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		log.WithError(err).WithField("Colour", s).Error("parseColor: could not parse colour")
+		return color.NRGBA{}, ErrInvalidColor
+	}
+
+	c := color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+	if len(s) == 8 {
+		c.A = uint8(v >> 24)
+	}
+
+	return c, nil
+}
+
+// parseTint returns the pixel colour mask for a Tiled tint colour, or nil for no tint.  An empty or invalid tint is
+// treated as no tint.
+func parseTint(s string) *pixel.RGBA {
+	if s == "" {
+		return nil
+	}
+
+	c, err := parseColor(s)
+	if err != nil {
+		log.WithError(err).WithField("Tint", s).Warn("parseTint: ignoring invalid tint colour")
+		return nil
+	}
+
+	mask := pixel.ToRGBA(c)
+	return &mask
+}
+
+// tintMask returns the colour mask for a tint returned by parseTint.  No tint is white, which has no effect when used as
+// a mask.
+func tintMask(tint *pixel.RGBA) pixel.RGBA {
+	if tint == nil {
+		return pixel.Alpha(1)
+	}
+	return *tint
+}